@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/qrtt1/doc-harvester/pkg/config"
+	"github.com/qrtt1/doc-harvester/pkg/extractor"
+	"github.com/qrtt1/doc-harvester/pkg/harvester"
+)
+
+// stringList is a flag.Value collecting the values of a repeatable flag.
+// The first value given on the command line replaces any list loaded from a config file.
+type stringList struct {
+	values *[]string
+	set    bool
+}
+
+func (l *stringList) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	if !l.set {
+		*l.values = nil
+		l.set = true
+	}
+	*l.values = append(*l.values, value)
+	return nil
+}
+
+// commaList is a flag.Value holding a comma-separated list
+type commaList struct {
+	values *[]string
+}
+
+func (l *commaList) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l *commaList) Set(value string) error {
+	*l.values = splitList(value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newFlagSet defines the command-line flags, bound to the fields of cfg.
+// The current values of cfg serve as defaults, so flags only override what they set explicitly.
+func newFlagSet(cfg *config.Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("harvester", flag.ExitOnError)
+
+	fs.BoolVar(&showVersion, "version", showVersion, "Print version information and exit")
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "Path to a YAML or JSON config file; flags override its values")
+	fs.StringVar(&cfg.ValidateFile, "validate", cfg.ValidateFile, "Check that an XML harvest file is well-formed and consistent, then exit")
+	fs.StringVar(&cfg.DiffFile, "diff", cfg.DiffFile, "Compare the -xml-output file against this earlier harvest and exit")
+	fs.Var(&commaList{values: &cfg.MergeFiles}, "merge", "Comma-separated XML harvest files to combine into the -output file, keeping the newest copy of each page, then exit")
+	fs.StringVar(&cfg.RetryFailedFrom, "retry-failed-from", cfg.RetryFailedFrom, "Fetch only the pages recorded as failed in this XML harvest again, update them in the file and exit")
+	fs.StringVar(&cfg.DiffJSON, "diff-json", cfg.DiffJSON, "With -diff, also write the comparison as JSON to this file")
+	fs.BoolVar(&cfg.ExploreOnly, "explore-only", cfg.ExploreOnly, "Only explore the website structure without downloading content")
+	fs.BoolVar(&cfg.HeadCheck, "head-check", cfg.HeadCheck, "Check every link of the crawled pages with HEAD requests and report broken ones, without downloading content")
+	fs.StringVar(&cfg.BaseURL, "base-url", cfg.BaseURL, "Resolve relative links as if the first URL's pages were served below this URL, e.g. the public address of a site fetched through a proxy")
+	fs.BoolVar(&cfg.SinglePage, "single-page", cfg.SinglePage, "Only download the given URLs; no links are discovered or followed")
+	fs.StringVar(&cfg.XMLOutput, "xml-output", cfg.XMLOutput, "Path to save content as a single XML file")
+	fs.StringVar(&cfg.XMLRootElement, "xml-root-element", cfg.XMLRootElement, "Name of the root element of the XML output (default: document)")
+	fs.StringVar(&cfg.XMLPageElement, "xml-page-element", cfg.XMLPageElement, "Name of the page elements of the XML output (default: page)")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "Output format: xml, single-markdown (one consolidated Markdown document) or jsonl (one JSON object per page and line, written as pages are fetched)")
+	fs.BoolFunc("emit-jsonl", "Stream each page as a JSON Lines record as soon as it is fetched; same as -format jsonl (use -output - for stdout)", func(string) error {
+		cfg.Format = config.FormatJSONL
+		return nil
+	})
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "Path of the output file (default: docs.xml, or docs.md for single-markdown); - writes the result to stdout")
+	fs.StringVar(&cfg.OutputDir, "output-dir", cfg.OutputDir, "Write index.xml (or index.md) plus an assets/ folder with downloaded images and files to this directory")
+	fs.StringVar(&cfg.Separator, "separator", cfg.Separator, "Text written between pages in single-markdown output (default: a --- rule)")
+	fs.BoolVar(&cfg.LocalLinks, "local-links", cfg.LocalLinks, "In single-markdown output, point links to other harvested pages to their headings, so the document can be navigated offline")
+	fs.BoolVar(&cfg.Debug, "debug", cfg.Debug, "Enable debug messages")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "Log format: text or json (one JSON object per line)")
+	fs.BoolVar(&cfg.Quiet, "quiet", cfg.Quiet, "Only print errors and the path of the result; overrides -debug")
+	fs.IntVar(&cfg.MaxDepth, "max-depth", cfg.MaxDepth, "Maximum depth for web crawling (0 means unlimited)")
+	fs.StringVar(&cfg.CrawlOrder, "crawl-order", cfg.CrawlOrder, "Order pages are fetched in: bfs (all pages of a depth first, so limits keep the site's breadth) or dfs (one branch at a time)")
+	fs.StringVar(&cfg.Scope, "scope", cfg.Scope, "Pages of the seed's host that are followed: parent (the seed's parent path and prompt-engineering pages, the original behavior) or subtree (the seed's directory and below)")
+	fs.IntVar(&cfg.MaxLinksPerPage, "max-links-per-page", cfg.MaxLinksPerPage, "Only follow the first this many accepted links of each page, bounding the fan-out of large index pages (0 means no limit)")
+	fs.IntVar(&cfg.MaxPathRepetition, "max-path-repetition", cfg.MaxPathRepetition, "Refuse links whose path repeats a segment more often than this, e.g. /a/b/a/b/a/b (0 means no limit)")
+	fs.BoolVar(&cfg.CollapseIndex, "collapse-index", cfg.CollapseIndex, "Treat /docs/index.html (index.htm, default.html) as the same page as /docs/")
+	fs.BoolVar(&cfg.LowercaseHost, "lowercase-host", cfg.LowercaseHost, "Ignore the case of host names when deduplicating URLs")
+	fs.BoolVar(&cfg.IncludeQueryPages, "include-query-pages", cfg.IncludeQueryPages, "Crawl URLs that differ from visited pages only by their query string (e.g. ?sort=date)")
+	fs.IntVar(&cfg.MaxPages, "max-pages", cfg.MaxPages, fmt.Sprintf("Stop after fetching this many pages (default with -max-depth 0: %d)", config.DefaultUnlimitedMaxPages))
+	fs.StringVar(&cfg.OnlyLang, "only-lang", cfg.OnlyLang, "Only save pages in the given language (e.g. en)")
+	fs.StringVar(&cfg.Since, "since", cfg.Since, "Only save pages whose Last-Modified header is not older than this date (e.g. 2024-01-01); pages without one are always saved")
+	fs.IntVar(&cfg.MaxContentChars, "max-content-chars", cfg.MaxContentChars, "Truncate the stored content of a page to this many characters of text (0 means unlimited)")
+	fs.BoolVar(&cfg.PruneEmpty, "prune-empty", cfg.PruneEmpty, "Don't store pages whose extracted text is shorter than -min-content-chars, like redirect shells and empty templates")
+	fs.IntVar(&cfg.MinContentChars, "min-content-chars", cfg.MinContentChars, "Text length below which -prune-empty skips a page")
+	fs.BoolVar(&cfg.DedupeBoilerplate, "dedupe-boilerplate", cfg.DedupeBoilerplate, "After the crawl, strip blocks (sidebars, footers, ...) repeated identically on more than -boilerplate-threshold of the pages")
+	fs.Float64Var(&cfg.BoilerplateThreshold, "boilerplate-threshold", cfg.BoilerplateThreshold, "Share of pages, between 0 and 1, a block must appear on to be stripped by -dedupe-boilerplate")
+	fs.BoolVar(&cfg.DumpRaw, "dump-raw", cfg.DumpRaw, "Also store the fetched HTML of each page, before extraction, in a rawContent element for debugging (roughly doubles the output size)")
+	fs.IntVar(&cfg.ExcerptChars, "excerpt-chars", cfg.ExcerptChars, "Length of the excerpt of the first paragraphs stored with each page (0 means none)")
+	fs.IntVar(&cfg.MaxTokens, "max-tokens", cfg.MaxTokens, "Stop the crawl once the saved pages reach this many estimated tokens (0 means unlimited)")
+	fs.Var(&cfg.MaxOutputSize, "max-output-size", "Stop the crawl before the content of the saved pages exceeds this size, e.g. 500MB (KB, MB and GB are powers of 1024)")
+	fs.StringVar(&cfg.FrontierFile, "frontier-file", cfg.FrontierFile, "Keep the visited URLs and the pages waiting to be fetched in this file instead of in memory, for very large crawls (replaced on each run)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", cfg.CacheDir, "Keep the fetched pages in this directory and parse them from there on later runs instead of fetching them, e.g. while tuning extraction settings (-render http only)")
+	fs.Var(&cfg.CacheTTL, "cache-ttl", "Age after which a cached page is fetched again (0s means cached pages don't expire)")
+	fs.BoolVar(&cfg.NoCache, "no-cache", cfg.NoCache, "Ignore the pages in -cache-dir, fetching every page again and refreshing the cache")
+	fs.Var(&cfg.MaxRuntime, "max-runtime", "Stop fetching new pages after this duration (e.g. 10m) and save what was harvested")
+	fs.BoolVar(&cfg.RespectNofollow, "respect-nofollow", cfg.RespectNofollow, "Don't follow links marked rel=\"nofollow\"")
+	fs.BoolVar(&cfg.RespectRobotsMeta, "respect-robots-meta", cfg.RespectRobotsMeta, "Skip noindex pages and don't follow links of nofollow pages (robots meta tag and X-Robots-Tag)")
+	fs.IntVar(&cfg.FollowExternalDepth, "follow-external-depth", cfg.FollowExternalDepth, "Also store pages on other hosts up to this many links away from the site; their links are not crawled further (0 means none)")
+	fs.BoolVar(&cfg.RespectCrawlDelay, "respect-crawl-delay", cfg.RespectCrawlDelay, "Wait the Crawl-delay of each host's robots.txt between requests to the host when it is longer than -delay")
+	fs.BoolVar(&cfg.RespectRobotsTxt, "respect-robots-txt", cfg.RespectRobotsTxt, "Skip the URLs each host's robots.txt disallows for the User-Agent")
+	fs.BoolVar(&cfg.FollowPagination, "follow-pagination", cfg.FollowPagination, "Follow rel=\"next\" page chains even outside the parent path (within max depth)")
+
+	fs.StringVar(&cfg.ContentSelector, "content-selector", cfg.ContentSelector, "CSS selector of the main content element (falls back to <article>, <main> or similar containers, then <body>, if nothing matches)")
+	fs.Var(&commaList{values: &cfg.ExtractionStrategies}, "extraction-strategies", "Comma-separated extraction strategies tried in order until one yields enough text: "+strings.Join(extractor.KnownStrategies, ", ")+" (default: the whole body, or the -content-selector element)")
+	fs.IntVar(&cfg.MinExtractionChars, "min-extraction-chars", cfg.MinExtractionChars, "Characters of text an extraction strategy must yield to be used (0 means any text)")
+	fs.Var(&stringList{values: &cfg.StripSelectors}, "strip-selector", "CSS selector of elements to remove from content (repeatable)")
+	fs.Var(&stringList{values: &cfg.ExcludeText}, "exclude-text", "Remove the innermost block containing this text, e.g. a deprecation or cookie notice; case-insensitive, or a regular expression written as /pattern/ (repeatable)")
+	fs.Var(&commaList{values: &cfg.RemoveTags}, "remove-tags", "Comma-separated tags to remove from content (default: "+strings.Join(extractor.DefaultRemoveTags, ",")+")")
+	fs.Var(&commaList{values: &cfg.KeepTags}, "keep-tags", "Comma-separated tags to keep even though they are removed by default (e.g. aside,header)")
+	fs.BoolVar(&cfg.NormalizeWhitespace, "normalize-whitespace", cfg.NormalizeWhitespace, "Collapse runs of whitespace in stored content and drop indentation between blocks; <pre> is kept as is")
+	fs.BoolVar(&cfg.StripComments, "strip-comments", cfg.StripComments, "Remove HTML comments from stored content (use -strip-comments=false to keep them)")
+
+	fs.BoolVar(&cfg.DownloadAssets, "download-assets", cfg.DownloadAssets, "Download images and embed them in the stored content")
+	fs.Int64Var(&cfg.MaxAssetSize, "max-asset-size", cfg.MaxAssetSize, "Maximum size of a downloaded asset in bytes")
+	fs.BoolVar(&cfg.DownloadFiles, "download-files", cfg.DownloadFiles, "Fetch linked files such as PDFs into a files/ directory next to the output (assets/ with -output-dir)")
+	fs.Var(&commaList{values: &cfg.DownloadExtensions}, "download-extensions", "Comma-separated extensions of links cataloged as downloads instead of crawled (default: "+strings.Join(harvester.DefaultDownloadExtensions, ",")+")")
+	fs.BoolVar(&cfg.AllowExternalAssets, "allow-external-assets", cfg.AllowExternalAssets, "Also download images hosted on other hosts (e.g. CDNs)")
+
+	fs.StringVar(&cfg.Render, "render", cfg.Render, "How pages are fetched: http, or js to render them in a headless Chrome for sites built with JavaScript")
+	fs.StringVar(&cfg.ChromePath, "chrome-path", cfg.ChromePath, "Chrome or Chromium executable for -render js (default: looked up in the usual locations)")
+	fs.Var(&commaList{values: &cfg.AcceptTypes}, "accept-types", "Comma-separated content types to parse and store, e.g. text/html,text/plain (default: all)")
+	fs.Var(&cfg.RequestTimeout, "request-timeout", "Deadline of a single request, including reading the response (0s means none)")
+	fs.StringVar(&cfg.PolitenessProfile, "politeness-profile", cfg.PolitenessProfile, "Preset of delay, concurrency, retries, User-Agent and robots settings: aggressive (16 in parallel, no delay), balanced (4 in parallel, 500ms delay) or polite (1 at a time, 2s delay, robots.txt, robots meta tags and nofollow honored); single flags override it")
+	fs.Var(&cfg.Delay, "delay", "Wait this long between two requests (e.g. 1s)")
+	fs.Var(&cfg.DelayJitter, "delay-jitter", "Vary the delay between requests randomly by up to this much in either direction (e.g. 400ms)")
+	fs.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "Number of pages fetched in parallel")
+	fs.IntVar(&cfg.ConcurrencyPerHost, "concurrency-per-host", cfg.ConcurrencyPerHost, "Maximum requests in flight to a single host (0 means no limit)")
+	fs.IntVar(&cfg.MaxRedirects, "max-redirects", cfg.MaxRedirects, "Maximum number of redirects followed per request")
+	fs.StringVar(&cfg.UserAgentFile, "user-agent-file", cfg.UserAgentFile, "File with one User-Agent per line; requests rotate through them")
+	fs.IntVar(&cfg.MaxIdleConnsPerHost, "max-idle-conns-per-host", cfg.MaxIdleConnsPerHost, "Idle HTTP connections kept open per host")
+	fs.Var(&cfg.IdleConnTimeout, "idle-conn-timeout", "How long an idle HTTP connection is kept open")
+	fs.BoolVar(&cfg.DisableKeepAlives, "disable-keep-alives", cfg.DisableKeepAlives, "Open a new HTTP connection for every request")
+	fs.BoolVar(&cfg.Insecure, "insecure", cfg.Insecure, "Accept any TLS certificate, e.g. a self-signed one of an internal server (insecure; prefer -ca-cert)")
+	fs.StringVar(&cfg.CACert, "ca-cert", cfg.CACert, "PEM file of certificate authorities to trust besides the system's, e.g. an internal CA")
+
+	fs.BoolVar(&cfg.RetryFailed, "retry-failed", cfg.RetryFailed, "Retry the pages that failed once the crawl is done")
+	fs.Var(&cfg.RetryTimeout, "retry-timeout", "Request timeout of the retry pass for failed pages")
+	fs.Var(&cfg.SlowThreshold, "slow-threshold", "Log pages taking longer than this to fetch and extract (e.g. 3s)")
+	fs.StringVar(&cfg.StatsJSON, "stats-json", cfg.StatsJSON, "Write the crawl statistics as JSON to this file")
+	fs.IntVar(&cfg.SaveEveryNPages, "save-every-n-pages", cfg.SaveEveryNPages, "Also save the XML output whenever this many pages were added since the last save, besides every 5 minutes (0 means on the timer only)")
+	fs.StringVar(&cfg.ExportSitemap, "export-sitemap", cfg.ExportSitemap, "Write a sitemap.xml of the successfully fetched pages to this file")
+
+	return fs
+}
+
+// parseConfig builds the configuration from defaults, the optional politeness profile,
+// the optional config file and the command-line flags, each overriding the ones before
+func parseConfig(args []string) (*config.Config, *flag.FlagSet, error) {
+	cfg := config.Default()
+	fs := newFlagSet(cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// The profile may be named by a flag or in the config file; the flag wins
+	profile := cfg.PolitenessProfile
+	if cfg.ConfigFile != "" && profile == "" {
+		fileCfg, err := config.Load(cfg.ConfigFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		profile = fileCfg.PolitenessProfile
+	}
+
+	// Apply the profile and load the config file on top of it, then parse the flags again so they take precedence
+	if profile != "" || cfg.ConfigFile != "" {
+		base := config.Default()
+		if err := base.ApplyPolitenessProfile(profile); err != nil {
+			return nil, nil, err
+		}
+		if cfg.ConfigFile != "" {
+			if _, err := config.LoadOnto(cfg.ConfigFile, base); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		fs = newFlagSet(base)
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, err
+		}
+		cfg = base
+	}
+
+	// Positional URLs replace the ones from the config file
+	if fs.NArg() > 0 {
+		cfg.URLs = fs.Args()
+	}
+
+	return cfg, fs, nil
+}
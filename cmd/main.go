@@ -1,66 +1,532 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/qrtt1/doc-harvester/pkg/config"
+	"github.com/qrtt1/doc-harvester/pkg/crawler"
+	"github.com/qrtt1/doc-harvester/pkg/extractor"
 	"github.com/qrtt1/doc-harvester/pkg/harvester"
+	"github.com/qrtt1/doc-harvester/pkg/storage"
 	"github.com/qrtt1/doc-harvester/pkg/tree"
 )
 
 // Global debug flag
 var debug bool
 
+// Set by the -version flag
+var showVersion bool
+
+// Build metadata, set at build time via -ldflags "-X main.version=..."
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// newLogger creates the logger writing to stderr in the given format.
+// JSON lines name the timestamp "ts", so they can be shipped to log aggregators as they are.
+func newLogger(format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format != config.LogFormatJSON {
+		return slog.New(slog.NewTextHandler(os.Stderr, opts))
+	}
+
+	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			a.Key = "ts"
+		}
+		return a
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+}
+
+// versionString describes the running build
+func versionString() string {
+	return fmt.Sprintf("harvester %s (commit %s, built %s)", version, commit, date)
+}
+
 // ExploreWebsite explores the website structure without downloading content
-func ExploreWebsite(urlStr string, maxDepth int) {
+func ExploreWebsite(cfg *config.Config) {
 	// Create website exploration context
-	explorerCtx, err := harvester.NewExplorerContext(urlStr, maxDepth, debug)
+	explorerCtx, err := harvester.NewExplorerContext(cfg.URLs[0], cfg.MaxDepth, debug)
 	if err != nil {
-		fmt.Printf("Failed to create explorer context: %s\n", err)
+		slog.Error("Failed to create explorer context", "error", err)
 		return
 	}
+	if !addSeedURLs(explorerCtx, cfg.URLs[1:]) {
+		return
+	}
+	if err := applyCrawlerConfig(explorerCtx, cfg); err != nil {
+		slog.Error("Failed to configure crawler", "error", err)
+		return
+	}
+	defer useRenderer(explorerCtx, cfg)()
+	explorerCtx.RespectNofollow = cfg.RespectNofollow
+	explorerCtx.MaxPathRepetition = cfg.MaxPathRepetition
+	explorerCtx.MaxLinksPerPage = cfg.MaxLinksPerPage
+	explorerCtx.Scope = cfg.Scope
+	explorerCtx.DownloadExtensions = cfg.DownloadExtensions
+	explorerCtx.WebTree.SetURLNormalization(urlNormalization(cfg))
+
+	ctx, cancel := runContext(cfg)
+	defer cancel()
 
 	// Perform website exploration
-	if err := explorerCtx.Explore(); err != nil {
-		fmt.Printf("Failed to explore website: %s\n", err)
+	links, err := explorerCtx.ExploreContext(ctx)
+	if err != nil {
+		slog.Error("Failed to explore website", "error", err)
+		return
+	}
+
+	// Print discovered parent URLs
+	if cfg.Quiet {
+		return
+	}
+	for _, link := range links {
+		fmt.Printf("<a href=\"%s\">\n", link)
 	}
 }
 
-// DownloadWebsite downloads website content and saves it locally
-func DownloadWebsite(url string, baseURL string, maxDepth int, xmlFilePath string) {
-	fmt.Printf("Using XML output file: %s\n", xmlFilePath)
+// CheckWebsiteLinks crawls the website and reports the status of every link found, without downloading content
+func CheckWebsiteLinks(cfg *config.Config) {
+	checkerCtx, err := harvester.NewExplorerContext(cfg.URLs[0], cfg.MaxDepth, debug)
+	if err != nil {
+		slog.Error("Failed to create link checker context", "error", err)
+		return
+	}
+	if !addSeedURLs(checkerCtx, cfg.URLs[1:]) {
+		return
+	}
+	checkerCtx.SinglePage = cfg.SinglePage
+	if err := applyCrawlerConfig(checkerCtx, cfg); err != nil {
+		slog.Error("Failed to configure crawler", "error", err)
+		return
+	}
+	defer useRenderer(checkerCtx, cfg)()
+	applyConfig(checkerCtx, cfg)
+
+	ctx, cancel := runContext(cfg)
+	defer cancel()
+
+	report, err := checkerCtx.CheckLinksContext(ctx)
+	if err != nil {
+		slog.Error("Failed to check links", "error", err)
+		return
+	}
+
+	fmt.Print(report.String())
+}
+
+// DownloadWebsite downloads website content from one or more seed URLs and saves it locally
+func DownloadWebsite(cfg *config.Config) {
+	outputPath := cfg.OutputPath()
+	slog.Info("Using output file", "path", outputPath, "format", cfg.Format)
 
 	// Ensure directory exists
-	dirPath := filepath.Dir(xmlFilePath)
+	dirPath := filepath.Dir(outputPath)
 	if dirPath != "." {
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			fmt.Printf("Failed to create directory for XML file: %v\n", err)
+			slog.Error("Failed to create directory for output file", "error", err)
 			return
 		}
 	}
 
-	// Create download context using XML storage
-	downloaderCtx, err := harvester.NewXMLDownloaderContext(url, xmlFilePath, baseURL, maxDepth, debug)
+	// Create download context using the storage of the output format
+	var downloaderCtx *harvester.HarvesterContext
+	var err error
+	switch {
+	case cfg.Format == config.FormatSingleMarkdown:
+		downloaderCtx, err = harvester.NewHarvesterContext(cfg.URLs[0],
+			harvester.WithMaxDepth(cfg.MaxDepth),
+			harvester.WithDebug(debug),
+			harvester.WithSingleMarkdownStorage(outputPath, cfg.Separator),
+		)
+	case cfg.Format == config.FormatJSONL && cfg.OutputToStdout():
+		// Records are streamed, so stdout is used from the start instead of once at the end
+		downloaderCtx, err = harvester.NewHarvesterContext(cfg.URLs[0],
+			harvester.WithMaxDepth(cfg.MaxDepth),
+			harvester.WithDebug(debug),
+			harvester.WithStorage(storage.NewJSONLWriterStorage(os.Stdout)),
+		)
+	case cfg.Format == config.FormatJSONL:
+		downloaderCtx, err = harvester.NewHarvesterContext(cfg.URLs[0],
+			harvester.WithMaxDepth(cfg.MaxDepth),
+			harvester.WithDebug(debug),
+			harvester.WithJSONLStorage(outputPath),
+		)
+	default:
+		downloaderCtx, err = harvester.NewXMLDownloaderContext(cfg.URLs[0], outputPath, cfg.URLs[0], cfg.MaxDepth, debug)
+	}
 	if err != nil {
-		fmt.Printf("Failed to create XML downloader context: %s\n", err)
+		slog.Error("Failed to create downloader context", "error", err)
 		return
 	}
+	if !addSeedURLs(downloaderCtx, cfg.URLs[1:]) {
+		return
+	}
+
+	// Fixed schemas may need other element names
+	if xmlStorage, ok := downloaderCtx.Storage.(*storage.XMLStorage); ok {
+		xmlStorage.RootElement = cfg.XMLRootElement
+		xmlStorage.PageElement = cfg.XMLPageElement
+		xmlStorage.SaveEveryNPages = cfg.SaveEveryNPages
+	}
+	if markdownStorage, ok := downloaderCtx.Storage.(*storage.SingleMarkdownStorage); ok {
+		markdownStorage.LocalLinks = cfg.LocalLinks
+	}
+
+	// With -output -, the result goes to stdout and everything else to stderr
+	report := os.Stdout
+	if cfg.OutputToStdout() {
+		switch s := downloaderCtx.Storage.(type) {
+		case *storage.XMLStorage:
+			s.Output = os.Stdout
+		case *storage.SingleMarkdownStorage:
+			s.Output = os.Stdout
+		}
+		report = os.Stderr
+	}
+
+	// An output directory keeps assets as files next to the index
+	if cfg.OutputDir != "" {
+		if xmlStorage, ok := downloaderCtx.Storage.(*storage.XMLStorage); ok {
+			xmlStorage.FilesDir = storage.AssetsDir
+		}
+		downloaderCtx.AssetFiles = true
+	}
 
 	// Set to download all pages
 	downloaderCtx.DownloadAll = true
+	downloaderCtx.SinglePage = cfg.SinglePage
+	if err := applyCrawlerConfig(downloaderCtx, cfg); err != nil {
+		slog.Error("Failed to configure crawler", "error", err)
+		return
+	}
+	defer useRenderer(downloaderCtx, cfg)()
+	applyConfig(downloaderCtx, cfg)
+	closeFrontier, err := useFrontierFile(downloaderCtx, cfg)
+	if err != nil {
+		slog.Error("Failed to set up the frontier file", "error", err)
+		return
+	}
+	defer closeFrontier()
 
-	// Execute download
-	if err := downloaderCtx.Download(); err != nil {
-		fmt.Printf("Failed to download website: %s\n", err)
+	ctx, cancel := runContext(cfg)
+	defer cancel()
+
+	// Execute download; when the time budget runs out, the pages harvested so far are saved
+	if err := downloaderCtx.DownloadContext(ctx); err != nil {
+		slog.Error("Failed to download website", "error", err)
 		return
 	}
 
-	// Cleanup work (save XML file)
+	// Cleanup work (save output file)
 	downloaderCtx.Cleanup()
 
-	fmt.Printf("XML download completed successfully. File saved to: %s\n", xmlFilePath)
+	if cfg.Quiet {
+		if !cfg.OutputToStdout() {
+			fmt.Println(outputPath)
+		}
+	} else {
+		if cfg.OutputToStdout() {
+			fmt.Fprintln(report, "Download completed successfully. Output written to stdout")
+		} else {
+			fmt.Fprintf(report, "Download completed successfully. File saved to: %s\n", outputPath)
+		}
+		fmt.Fprint(report, downloaderCtx.Stats.String())
+		for _, u := range downloaderCtx.FailedURLs {
+			fmt.Fprintf(report, "Failed: %s\n", u)
+		}
+	}
+
+	if cfg.StatsJSON != "" {
+		if err := writeJSON(cfg.StatsJSON, &downloaderCtx.Stats); err != nil {
+			slog.Error("Failed to write crawl statistics", "error", err)
+		}
+	}
+
+	if cfg.ExportSitemap != "" {
+		if xmlStorage, ok := downloaderCtx.Storage.(*storage.XMLStorage); ok {
+			if err := writeSitemap(cfg.ExportSitemap, xmlStorage); err != nil {
+				slog.Error("Failed to write sitemap", "error", err)
+			}
+		}
+	}
+}
+
+// RetryFailedPages fetches the pages recorded as failed in an earlier XML harvest again and updates them in the file
+func RetryFailedPages(cfg *config.Config) error {
+	path := cfg.RetryFailedFrom
+	xmlStorage, err := storage.OpenXMLStorage(path)
+	if err != nil {
+		return err
+	}
+	failed := xmlStorage.Document.FailedURLs()
+	if len(failed) == 0 {
+		xmlStorage.StopAutoSave()
+		fmt.Printf("%s has no failed pages\n", path)
+		return nil
+	}
+
+	rootURL := xmlStorage.Document.RootURL
+	if rootURL == "" {
+		rootURL = failed[0]
+	}
+	retryCtx, err := harvester.NewHarvesterContext(rootURL,
+		harvester.WithDebug(debug),
+		harvester.WithStorage(xmlStorage),
+	)
+	if err != nil {
+		xmlStorage.StopAutoSave()
+		return fmt.Errorf("failed to create downloader context: %v", err)
+	}
+	xmlStorage.SaveEveryNPages = cfg.SaveEveryNPages
+	retryCtx.DownloadAll = true
+	if err := applyCrawlerConfig(retryCtx, cfg); err != nil {
+		xmlStorage.StopAutoSave()
+		return fmt.Errorf("failed to configure crawler: %v", err)
+	}
+	defer useRenderer(retryCtx, cfg)()
+	applyConfig(retryCtx, cfg)
+
+	ctx, cancel := runContext(cfg)
+	defer cancel()
+
+	slog.Info("Retrying failed pages", "file", path, "count", len(failed))
+	recovered := retryCtx.RetryPagesContext(ctx, failed)
+	retryCtx.Cleanup()
+
+	if cfg.Quiet {
+		fmt.Println(path)
+		return nil
+	}
+	fmt.Printf("Recovered %d of %d failed pages. File updated: %s\n", recovered, len(failed), path)
+	for _, u := range retryCtx.FailedURLs {
+		fmt.Printf("Still failing: %s\n", u)
+	}
+	return nil
+}
+
+// writeSitemap writes a sitemap.xml of the pages in an XML storage to a file
+func writeSitemap(path string, s *storage.XMLStorage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	if err := s.GenerateSitemap(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DiffHarvests compares an earlier harvest with the configured XML output and prints the changes
+func DiffHarvests(cfg *config.Config) error {
+	result, err := storage.Diff(cfg.DiffFile, cfg.OutputPath())
+	if err != nil {
+		return err
+	}
+
+	for _, u := range result.Added {
+		fmt.Printf("+ %s\n", u)
+	}
+	for _, u := range result.Removed {
+		fmt.Printf("- %s\n", u)
+	}
+	for _, u := range result.Changed {
+		fmt.Printf("~ %s\n", u)
+	}
+	fmt.Printf("%d added, %d removed, %d changed\n", len(result.Added), len(result.Removed), len(result.Changed))
+
+	if cfg.DiffJSON != "" {
+		return writeJSON(cfg.DiffJSON, result)
+	}
+
+	return nil
+}
+
+// writeJSON writes a value as indented JSON to a file
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// runContext returns the context of a run, limited to the configured maximum runtime
+func runContext(cfg *config.Config) (context.Context, context.CancelFunc) {
+	if cfg.MaxRuntime <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), time.Duration(cfg.MaxRuntime))
+}
+
+// applyCrawlerConfig copies the HTTP-related options of the configuration onto the context's crawler
+// and logs in if the configuration has an auth section
+func applyCrawlerConfig(hc *harvester.HarvesterContext, cfg *config.Config) error {
+	c, ok := hc.Crawler.(*crawler.Crawler)
+	if !ok {
+		return nil
+	}
+
+	c.UserAgents = cfg.UserAgents
+	c.RequestTimeout = time.Duration(cfg.RequestTimeout)
+	c.MaxRedirects = cfg.MaxRedirects
+	c.MaxConcurrencyPerHost = cfg.ConcurrencyPerHost
+	c.RequestDelay = time.Duration(cfg.Delay)
+	c.RequestDelayJitter = time.Duration(cfg.DelayJitter)
+	c.RespectCrawlDelay = cfg.RespectCrawlDelay
+	c.RespectRobotsTxt = cfg.RespectRobotsTxt
+	c.AcceptContentTypes = cfg.AcceptTypes
+	c.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	c.IdleConnTimeout = time.Duration(cfg.IdleConnTimeout)
+	c.DisableKeepAlives = cfg.DisableKeepAlives
+	c.ProxyRules = cfg.ProxyRules
+	c.InsecureSkipVerify = cfg.Insecure
+	if cfg.Insecure {
+		slog.Warn("TLS certificate verification is disabled")
+	}
+	if cfg.CACert != "" {
+		rootCAs, err := crawler.LoadCACert(cfg.CACert)
+		if err != nil {
+			return err
+		}
+		c.RootCAs = rootCAs
+	}
+	if cfg.CacheDir != "" {
+		cache, err := crawler.NewPageCache(cfg.CacheDir, time.Duration(cfg.CacheTTL))
+		if err != nil {
+			return err
+		}
+		cache.Refresh = cfg.NoCache
+		c.Cache = cache
+	}
+	c.ResetTransport()
+
+	if cfg.Auth != nil && cfg.Auth.LoginURL != "" {
+		slog.Info("Logging in", "url", cfg.Auth.LoginURL)
+		if err := c.LoginForm(cfg.Auth.LoginURL, cfg.Auth.Fields); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// useRenderer makes a context render pages in a headless browser if the configuration asks for it.
+// The returned function shuts the browser down.
+func useRenderer(hc *harvester.HarvesterContext, cfg *config.Config) func() {
+	c, ok := hc.Crawler.(*crawler.Crawler)
+	if !ok || cfg.Render != config.RenderJS {
+		return func() {}
+	}
+
+	renderer := crawler.NewRenderer(c)
+	renderer.ExecPath = cfg.ChromePath
+	hc.Crawler = renderer
+	return func() { renderer.Close() }
+}
+
+// useFrontierFile keeps the crawl frontier of a context in the configured file, if any.
+// The returned function closes the file.
+func useFrontierFile(hc *harvester.HarvesterContext, cfg *config.Config) (func(), error) {
+	if cfg.FrontierFile == "" {
+		return func() {}, nil
+	}
+
+	frontier, err := tree.NewDiskFrontier(cfg.FrontierFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := hc.WebTree.SetFrontier(frontier); err != nil {
+		frontier.Close()
+		return nil, err
+	}
+	return func() { frontier.Close() }, nil
+}
+
+// applyConfig copies the content-related options of the configuration onto a context
+func applyConfig(hc *harvester.HarvesterContext, cfg *config.Config) {
+	if cfg.BaseURL != "" {
+		hc.BaseURL = cfg.BaseURL
+	}
+	hc.OnlyLang = cfg.OnlyLang
+	hc.Since, _ = cfg.SinceTime() // Checked by Validate
+	hc.MaxPages = cfg.EffectiveMaxPages()
+	hc.MaxPathRepetition = cfg.MaxPathRepetition
+	hc.MaxLinksPerPage = cfg.MaxLinksPerPage
+	hc.CrawlOrder = cfg.CrawlOrder
+	hc.Scope = cfg.Scope
+	hc.FollowExternalDepth = cfg.FollowExternalDepth
+	hc.MaxConcurrency = cfg.Concurrency
+	hc.WebTree.SetURLNormalization(urlNormalization(cfg))
+	hc.MaxTokens = cfg.MaxTokens
+	hc.MaxOutputSize = int64(cfg.MaxOutputSize)
+	hc.MaxContentChars = cfg.MaxContentChars
+	hc.PruneEmpty = cfg.PruneEmpty
+	if cfg.DedupeBoilerplate {
+		hc.BoilerplateShare = cfg.BoilerplateThreshold
+	}
+	hc.MinContentChars = cfg.MinContentChars
+	hc.ExcerptChars = cfg.ExcerptChars
+	hc.DumpRaw = cfg.DumpRaw
+	hc.FollowPagination = cfg.FollowPagination
+	hc.RespectRobotsMeta = cfg.RespectRobotsMeta
+	hc.RespectNofollow = cfg.RespectNofollow
+	if contentExtractor, ok := hc.Extractor.(*extractor.ContentExtractor); ok {
+		contentExtractor.ContentSelector = cfg.ContentSelector
+		contentExtractor.StripSelectors = cfg.StripSelectors
+		contentExtractor.ExcludeText = cfg.ExcludeText
+		contentExtractor.StripComments = cfg.StripComments
+		contentExtractor.NormalizeWhitespace = cfg.NormalizeWhitespace
+		contentExtractor.Strategies = cfg.ExtractionStrategies
+		contentExtractor.MinStrategyChars = cfg.MinExtractionChars
+		if cfg.RemoveTags != nil {
+			contentExtractor.RemoveTags = cfg.RemoveTags
+		}
+		if len(cfg.KeepTags) > 0 {
+			contentExtractor.KeepTags(cfg.KeepTags)
+		}
+	}
+	hc.DownloadAssets = cfg.DownloadAssets
+	hc.MaxAssetSize = cfg.MaxAssetSize
+	hc.AllowExternalAssets = cfg.AllowExternalAssets
+	hc.RetryFailed = cfg.RetryFailed
+	hc.SlowThreshold = time.Duration(cfg.SlowThreshold)
+	hc.RetryTimeout = time.Duration(cfg.RetryTimeout)
+	hc.DownloadFiles = cfg.DownloadFiles
+	hc.DownloadExtensions = cfg.DownloadExtensions
+}
+
+// urlNormalization returns the URL equivalences of the configuration
+func urlNormalization(cfg *config.Config) tree.URLNormalization {
+	return tree.URLNormalization{
+		CollapseIndexFiles: cfg.CollapseIndex,
+		LowercaseHost:      cfg.LowercaseHost,
+		IgnoreQuery:        !cfg.IncludeQueryPages,
+	}
+}
+
+// addSeedURLs adds additional seed URLs to a context, reporting whether all of them were valid
+func addSeedURLs(hc *harvester.HarvesterContext, urls []string) bool {
+	for _, seedURL := range urls {
+		if err := hc.AddSeedURL(seedURL); err != nil {
+			slog.Error("Invalid seed URL", "url", seedURL, "error", err)
+			return false
+		}
+	}
+	return true
 }
 
 // getDomain extracts domain from URL
@@ -78,39 +544,102 @@ func getDomain(url string) string {
 }
 
 func main() {
-	// Define CLI flags
-	exploreOnly := flag.Bool("explore-only", false, "Only explore the website structure without downloading content")
-	xmlOutput := flag.String("xml-output", "", "Path to save content as a single XML file")
-	debugFlag := flag.Bool("debug", false, "Enable debug messages")
-	maxDepth := flag.Int("max-depth", 2, "Maximum depth for web crawling (default: 2)")
-
-	// Parse CLI flags
-	flag.Parse()
-
-	// Set global debug flag
-	debug = *debugFlag
-
-	// Validate arguments
-	if len(flag.Args()) < 1 {
-		fmt.Println("Usage: harvester [options] <URL>")
-		flag.PrintDefaults()
+	// Parse the config file and CLI flags
+	cfg, fs, err := parseConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
-	url := flag.Args()[0]
+	if showVersion {
+		fmt.Println(versionString())
+		return
+	}
 
-	// Determine the XML output file path
-	xmlFilePath := "docs.xml"
-	if *xmlOutput != "" {
-		xmlFilePath = *xmlOutput
+	// Set global debug flag; quiet mode overrides it
+	debug = cfg.Debug && !cfg.Quiet
+
+	// Log to stderr, honoring the debug and quiet flags as the level
+	logLevel := slog.LevelInfo
+	switch {
+	case cfg.Quiet:
+		logLevel = slog.LevelError
+	case debug:
+		logLevel = slog.LevelDebug
+	}
+	slog.SetDefault(newLogger(cfg.LogFormat, logLevel))
+	slog.Debug(versionString())
+
+	// Check an XML file instead of crawling
+	if cfg.ValidateFile != "" {
+		if err := storage.ValidateFile(cfg.ValidateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "%s is invalid:\n%v\n", cfg.ValidateFile, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid\n", cfg.ValidateFile)
+		return
+	}
+
+	// Merging and comparing work on files only
+	if cfg.OutputToStdout() && (len(cfg.MergeFiles) > 0 || cfg.DiffFile != "") {
+		fmt.Fprintf(os.Stderr, "-output - can't be used with -merge or -diff\n")
+		os.Exit(1)
+	}
+
+	// Combine harvests instead of crawling
+	if len(cfg.MergeFiles) > 0 {
+		if err := storage.Merge(cfg.MergeFiles, cfg.OutputPath()); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Merged %d files into %s\n", len(cfg.MergeFiles), cfg.OutputPath())
+		return
+	}
+
+	// Compare two harvests instead of crawling
+	if cfg.DiffFile != "" {
+		if err := DiffHarvests(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Validate arguments; retrying failures takes the URLs from the file
+	if len(cfg.URLs) < 1 && cfg.RetryFailedFrom == "" {
+		fmt.Println("Usage: harvester [options] <URL> [URL...]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.UserAgentFile != "" {
+		agents, err := crawler.ReadUserAgents(cfg.UserAgentFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		cfg.UserAgents = append(cfg.UserAgents, agents...)
 	}
 
 	// Handle the download logic
-	if *exploreOnly {
-		fmt.Printf("Exploring website structure for URL: %s with max depth: %d\n", url, *maxDepth)
-		ExploreWebsite(url, *maxDepth)
+	if cfg.RetryFailedFrom != "" {
+		if err := RetryFailedPages(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	} else if cfg.HeadCheck {
+		slog.Info("Checking links", "urls", cfg.URLs, "maxDepth", cfg.MaxDepth)
+		CheckWebsiteLinks(cfg)
+	} else if cfg.ExploreOnly {
+		slog.Info("Exploring website structure", "urls", cfg.URLs, "maxDepth", cfg.MaxDepth)
+		ExploreWebsite(cfg)
 	} else {
-		fmt.Printf("Downloading content from URL: %s to XML file: %s with max depth: %d\n", url, xmlFilePath, *maxDepth)
-		DownloadWebsite(url, url, *maxDepth, xmlFilePath)
+		slog.Info("Downloading content", "urls", cfg.URLs, "output", cfg.OutputPath(), "maxDepth", cfg.MaxDepth)
+		DownloadWebsite(cfg)
 	}
 }
@@ -0,0 +1,537 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/qrtt1/doc-harvester/pkg/crawler"
+	"github.com/qrtt1/doc-harvester/pkg/extractor"
+	"github.com/qrtt1/doc-harvester/pkg/harvester"
+	"github.com/qrtt1/doc-harvester/pkg/node"
+	"github.com/qrtt1/doc-harvester/pkg/storage"
+)
+
+// Config holds all options of a harvest run.
+// It is populated from defaults, then an optional YAML/JSON config file, then command-line flags.
+type Config struct {
+	URLs        []string `yaml:"urls" json:"urls"`               // Seed URLs
+	BaseURL     string   `yaml:"baseUrl" json:"baseUrl"`         // URL relative links resolve against, if different from the first seed
+	ExploreOnly bool     `yaml:"exploreOnly" json:"exploreOnly"` // Only explore the website structure
+	HeadCheck   bool     `yaml:"headCheck" json:"headCheck"`     // Check the links of the crawled pages instead of downloading them
+	SinglePage  bool     `yaml:"singlePage" json:"singlePage"`   // Only download the given URLs, without following links
+	XMLOutput   string   `yaml:"xmlOutput" json:"xmlOutput"`     // Path of the XML output file
+	Format      string   `yaml:"format" json:"format"`           // Output format: xml, single-markdown or jsonl
+	Output      string   `yaml:"output" json:"output"`           // Path of the output file (overrides XMLOutput)
+	OutputDir   string   `yaml:"outputDir" json:"outputDir"`     // Directory of an index file plus an assets folder (instead of Output)
+	Separator   string   `yaml:"separator" json:"separator"`     // Text between pages in single-markdown output
+	LocalLinks  bool     `yaml:"localLinks" json:"localLinks"`   // Point links between harvested pages to their headings in single-markdown output
+	Debug       bool     `yaml:"debug" json:"debug"`             // Enable debug messages
+	Quiet       bool     `yaml:"quiet" json:"quiet"`             // Only print errors and the path of the result (overrides Debug)
+	LogFormat   string   `yaml:"logFormat" json:"logFormat"`     // Log format: text or json
+	MaxDepth    int      `yaml:"maxDepth" json:"maxDepth"`       // Maximum crawling depth (0 or less means unlimited)
+	MaxPages    int      `yaml:"maxPages" json:"maxPages"`       // Maximum number of pages fetched (0 means unlimited, see EffectiveMaxPages)
+	OnlyLang    string   `yaml:"onlyLang" json:"onlyLang"`       // Only save pages in this language
+	Since       string   `yaml:"since" json:"since"`             // Only save pages modified since this date (YYYY-MM-DD or RFC 3339), see SinceTime
+
+	XMLRootElement string `yaml:"xmlRootElement" json:"xmlRootElement"` // Name of the root element of XML output (default: document)
+	XMLPageElement string `yaml:"xmlPageElement" json:"xmlPageElement"` // Name of the page elements of XML output (default: page)
+
+	FollowPagination  bool     `yaml:"followPagination" json:"followPagination"`   // Follow rel="next" chains beyond the parent-path filter
+	MaxContentChars   int      `yaml:"maxContentChars" json:"maxContentChars"`     // Truncate stored content to this many characters of text
+	MaxTokens         int      `yaml:"maxTokens" json:"maxTokens"`                 // Stop the crawl once saved pages reach this many estimated tokens
+	MaxOutputSize     ByteSize `yaml:"maxOutputSize" json:"maxOutputSize"`         // Stop the crawl before the saved content exceeds this size, e.g. "500MB"
+	PruneEmpty        bool     `yaml:"pruneEmpty" json:"pruneEmpty"`               // Skip storing pages with less than MinContentChars characters of text
+	MinContentChars   int      `yaml:"minContentChars" json:"minContentChars"`     // Text length below which PruneEmpty skips a page
+	ExcerptChars      int      `yaml:"excerptChars" json:"excerptChars"`           // Length of the excerpt stored with each page (0 means none)
+	DumpRaw           bool     `yaml:"dumpRaw" json:"dumpRaw"`                     // Also store the fetched HTML of each page in a rawContent element
+	MaxRuntime        Duration `yaml:"maxRuntime" json:"maxRuntime"`               // Stop fetching new pages after this wall-clock time (0 means unlimited)
+	RespectNofollow   bool     `yaml:"respectNofollow" json:"respectNofollow"`     // Don't follow links marked rel="nofollow"
+	RespectRobotsMeta bool     `yaml:"respectRobotsMeta" json:"respectRobotsMeta"` // Honor noindex/nofollow robots meta tags and X-Robots-Tag headers
+	RespectCrawlDelay bool     `yaml:"respectCrawlDelay" json:"respectCrawlDelay"` // Honor the Crawl-delay of each host's robots.txt
+	RespectRobotsTxt  bool     `yaml:"respectRobotsTxt" json:"respectRobotsTxt"`   // Skip URLs disallowed by each host's robots.txt
+	MaxPathRepetition int      `yaml:"maxPathRepetition" json:"maxPathRepetition"` // Refuse links repeating a path segment more often (0 means no limit)
+	MaxLinksPerPage   int      `yaml:"maxLinksPerPage" json:"maxLinksPerPage"`     // Only follow the first this many accepted links of each page (0 means no limit)
+	CrawlOrder        string   `yaml:"crawlOrder" json:"crawlOrder"`               // Order pages are fetched in: bfs or dfs
+	Scope             string   `yaml:"scope" json:"scope"`                         // Pages of the seed's host that are followed: parent or subtree
+	CollapseIndex     bool     `yaml:"collapseIndex" json:"collapseIndex"`         // Treat /docs/index.html as the same page as /docs/
+	LowercaseHost     bool     `yaml:"lowercaseHost" json:"lowercaseHost"`         // Ignore the case of host names when deduplicating URLs
+	IncludeQueryPages bool     `yaml:"includeQueryPages" json:"includeQueryPages"` // Crawl URLs differing from visited pages only by query string
+
+	FollowExternalDepth int `yaml:"followExternalDepth" json:"followExternalDepth"` // Hops to pages on other hosts (0 means none)
+
+	DedupeBoilerplate    bool    `yaml:"dedupeBoilerplate" json:"dedupeBoilerplate"`       // Strip blocks repeated across pages before the output is written
+	BoilerplateThreshold float64 `yaml:"boilerplateThreshold" json:"boilerplateThreshold"` // Share of pages (0-1) a block must exceed to count as boilerplate
+
+	ContentSelector string   `yaml:"contentSelector" json:"contentSelector"` // CSS selector of the main content element
+	StripSelectors  []string `yaml:"stripSelectors" json:"stripSelectors"`   // CSS selectors of elements to remove
+	ExcludeText     []string `yaml:"excludeText" json:"excludeText"`         // Text (or /regexp/) whose innermost block is removed
+	RemoveTags      []string `yaml:"removeTags" json:"removeTags"`           // Tags to remove from content (nil keeps the default list)
+	KeepTags        []string `yaml:"keepTags" json:"keepTags"`               // Tags to keep even though removed by default
+	StripComments   bool     `yaml:"stripComments" json:"stripComments"`     // Remove HTML comments from content
+
+	NormalizeWhitespace bool `yaml:"normalizeWhitespace" json:"normalizeWhitespace"` // Collapse whitespace in content, except in <pre>
+
+	ExtractionStrategies []string `yaml:"extractionStrategies" json:"extractionStrategies"` // Extraction strategies tried in order (nil extracts the body, or uses the selector)
+	MinExtractionChars   int      `yaml:"minExtractionChars" json:"minExtractionChars"`     // Text a strategy must yield to be used
+
+	DownloadAssets      bool  `yaml:"downloadAssets" json:"downloadAssets"`           // Download and embed images
+	MaxAssetSize        int64 `yaml:"maxAssetSize" json:"maxAssetSize"`               // Maximum asset size in bytes
+	AllowExternalAssets bool  `yaml:"allowExternalAssets" json:"allowExternalAssets"` // Download images from other hosts
+
+	DownloadFiles      bool     `yaml:"downloadFiles" json:"downloadFiles"`           // Fetch linked files such as PDFs next to the output
+	DownloadExtensions []string `yaml:"downloadExtensions" json:"downloadExtensions"` // Extensions of links cataloged as downloads (nil keeps the default list)
+
+	PolitenessProfile string `yaml:"politenessProfile" json:"politenessProfile"` // Preset of delay, concurrency, retries and robots settings, see ApplyPolitenessProfile
+
+	RequestTimeout Duration `yaml:"requestTimeout" json:"requestTimeout"` // Deadline of a single request
+	MaxRedirects   int      `yaml:"maxRedirects" json:"maxRedirects"`     // Maximum redirects followed per request
+	Delay          Duration `yaml:"delay" json:"delay"`                   // Base delay between two requests
+	DelayJitter    Duration `yaml:"delayJitter" json:"delayJitter"`       // Random deviation from Delay in either direction
+	AcceptTypes    []string `yaml:"acceptTypes" json:"acceptTypes"`       // Content types parsed and stored (empty accepts all)
+	UserAgents     []string `yaml:"userAgents" json:"userAgents"`         // User-Agent strings to rotate through
+	UserAgentFile  string   `yaml:"userAgentFile" json:"userAgentFile"`   // File with one User-Agent per line, added to UserAgents
+	Render         string   `yaml:"render" json:"render"`                 // How pages are fetched: RenderHTTP or RenderJS
+	ChromePath     string   `yaml:"chromePath" json:"chromePath"`         // Browser executable for RenderJS (default: looked up)
+
+	MaxIdleConnsPerHost int      `yaml:"maxIdleConnsPerHost" json:"maxIdleConnsPerHost"` // Idle connections kept open per host
+	IdleConnTimeout     Duration `yaml:"idleConnTimeout" json:"idleConnTimeout"`         // How long idle connections are kept open
+	DisableKeepAlives   bool     `yaml:"disableKeepAlives" json:"disableKeepAlives"`     // Use a new connection for every request
+
+	Insecure bool   `yaml:"insecure" json:"insecure"` // Accept any TLS certificate, e.g. a self-signed one
+	CACert   string `yaml:"caCert" json:"caCert"`     // PEM file of certificate authorities to trust besides the system's
+
+	Concurrency        int `yaml:"concurrency" json:"concurrency"`               // Pages fetched in parallel
+	ConcurrencyPerHost int `yaml:"concurrencyPerHost" json:"concurrencyPerHost"` // Requests in flight per host (0 means no limit)
+
+	FrontierFile string `yaml:"frontierFile" json:"frontierFile"` // Keep visited URLs and pending pages in this file instead of memory
+
+	CacheDir string   `yaml:"cacheDir" json:"cacheDir"` // Keep fetched pages in this directory and parse them from there on later runs
+	CacheTTL Duration `yaml:"cacheTtl" json:"cacheTtl"` // Age after which cached pages are fetched again (0 means they don't expire)
+	NoCache  bool     `yaml:"noCache" json:"noCache"`   // Ignore cached pages, fetching every page again and refreshing the cache
+
+	StatsJSON     string   `yaml:"statsJson" json:"statsJson"`         // Where to write the crawl statistics as JSON
+	ExportSitemap string   `yaml:"exportSitemap" json:"exportSitemap"` // Where to write a sitemap.xml of the fetched pages
+	SlowThreshold Duration `yaml:"slowThreshold" json:"slowThreshold"` // Log pages taking longer to fetch and extract
+
+	SaveEveryNPages int `yaml:"saveEveryNPages" json:"saveEveryNPages"` // Also save the XML output after this many new pages (0 means every 5 minutes only)
+
+	RetryFailed  bool     `yaml:"retryFailed" json:"retryFailed"`   // Retry failed pages once the crawl is done
+	RetryTimeout Duration `yaml:"retryTimeout" json:"retryTimeout"` // Request timeout of the retry pass
+
+	Auth       *AuthConfig       `yaml:"auth" json:"auth"`             // Login form submitted before crawling (config file only)
+	ProxyRules map[string]string `yaml:"proxyRules" json:"proxyRules"` // Proxy URL per host pattern (config file only)
+
+	ConfigFile   string `yaml:"-" json:"-"` // Path of the config file this configuration was loaded from
+	DiffFile     string `yaml:"-" json:"-"` // Compare XMLOutput against this earlier harvest instead of crawling
+	DiffJSON     string `yaml:"-" json:"-"` // Where to write the comparison as JSON
+	ValidateFile string `yaml:"-" json:"-"` // Check this XML file and exit instead of crawling
+
+	RetryFailedFrom string `yaml:"-" json:"-"` // Fetch the failed pages of this XML harvest again and update it instead of crawling
+
+	MergeFiles []string `yaml:"-" json:"-"` // Combine these XML files into the output file instead of crawling
+}
+
+// AuthConfig describes a login form to submit before crawling
+type AuthConfig struct {
+	LoginURL string            `yaml:"loginUrl" json:"loginUrl"` // URL the form is POSTed to
+	Fields   map[string]string `yaml:"fields" json:"fields"`     // Form fields, e.g. username and password
+}
+
+// DefaultUnlimitedMaxPages guards crawls with unlimited depth that set no page limit
+const DefaultUnlimitedMaxPages = 1000
+
+// SinceTime returns the time of Since, or the zero time if it is not set.
+// A date without a time means midnight UTC.
+func (c *Config) SinceTime() (time.Time, error) {
+	if c.Since == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, c.Since); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, c.Since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since date %q (use YYYY-MM-DD or RFC 3339)", c.Since)
+	}
+	return t, nil
+}
+
+// EffectiveMaxPages returns the page limit of a crawl.
+// Crawls with unlimited depth get DefaultUnlimitedMaxPages unless a limit is set, so they can't run forever by accident.
+func (c *Config) EffectiveMaxPages() int {
+	if c.MaxPages <= 0 && c.MaxDepth <= 0 {
+		return DefaultUnlimitedMaxPages
+	}
+	return c.MaxPages
+}
+
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// xmlNamePattern matches the element names allowed for XML output; namespace prefixes are not supported
+var xmlNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// Page fetching backends
+const (
+	RenderHTTP = "http" // Plain HTTP requests
+	RenderJS   = "js"   // A headless browser running the page's JavaScript
+)
+
+// Output formats
+const (
+	FormatXML            = "xml"
+	FormatSingleMarkdown = "single-markdown"
+	FormatJSONL          = "jsonl" // One JSON object per page and line, written as pages are fetched
+)
+
+// Politeness profiles
+const (
+	ProfileAggressive = "aggressive" // As fast as the site allows: 16 pages in parallel, no delay, no retries
+	ProfileBalanced   = "balanced"   // In between: 4 pages in parallel, at most 2 per host, with a short delay
+	ProfilePolite     = "polite"     // One page at a time with a 2s delay, honoring robots rules
+)
+
+// PoliteUserAgent is the User-Agent of the polite profile, identifying the crawler to site owners
+const PoliteUserAgent = "doc-harvester (+https://github.com/qrtt1/doc-harvester)"
+
+// StdoutOutput is the Output value that writes the result to standard output
+const StdoutOutput = "-"
+
+// OutputToStdout reports whether the result is written to standard output instead of a file
+func (c *Config) OutputToStdout() bool {
+	return c.Output == StdoutOutput && c.OutputDir == ""
+}
+
+// OutputPath returns the path of the output file; in an output directory, that's its index file
+func (c *Config) OutputPath() string {
+	if c.OutputDir != "" {
+		switch c.Format {
+		case FormatSingleMarkdown:
+			return filepath.Join(c.OutputDir, "index.md")
+		case FormatJSONL:
+			return filepath.Join(c.OutputDir, "index.jsonl")
+		}
+		return filepath.Join(c.OutputDir, "index.xml")
+	}
+	if c.Output != "" {
+		return c.Output
+	}
+	switch c.Format {
+	case FormatSingleMarkdown:
+		return "docs.md"
+	case FormatJSONL:
+		return "docs.jsonl"
+	}
+	return c.XMLOutput
+}
+
+// Duration is a time.Duration written as a string such as "10m" in config files and flags
+type Duration time.Duration
+
+// String formats the duration, e.g. "10m0s"
+func (d *Duration) String() string {
+	return time.Duration(*d).String()
+}
+
+// Set parses a duration string such as "90s" or "1h30m"
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %v", value, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, used by the YAML and JSON decoders
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+// ByteSize is a number of bytes written as a string such as "500MB" in config files and flags.
+// The units KB, MB and GB are powers of 1024; a plain number is bytes.
+type ByteSize int64
+
+// byteUnits are the units of ByteSize, largest first
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// String formats the size with the largest unit dividing it, e.g. "500MB"
+func (b *ByteSize) String() string {
+	for _, unit := range byteUnits {
+		if *b != 0 && int64(*b)%unit.size == 0 {
+			return strconv.FormatInt(int64(*b)/unit.size, 10) + unit.suffix
+		}
+	}
+	return "0"
+}
+
+// Set parses a size such as "500MB", "1.5GB" or "4096"
+func (b *ByteSize) Set(value string) error {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q (use e.g. 500MB)", value)
+	}
+	*b = ByteSize(n * float64(multiplier))
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, used by the YAML and JSON decoders
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	return b.Set(string(text))
+}
+
+// Default returns the configuration used when no file or flag overrides a value
+func Default() *Config {
+	return &Config{
+		XMLOutput:    "docs.xml",
+		Format:       FormatXML,
+		LogFormat:    LogFormatText,
+		Render:       RenderHTTP,
+		MaxDepth:     2,
+		MaxAssetSize: 5 * 1024 * 1024,
+
+		RequestTimeout:      Duration(crawler.DefaultRequestTimeout),
+		MaxRedirects:        crawler.DefaultMaxRedirects,
+		Concurrency:         1,
+		MaxIdleConnsPerHost: crawler.DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     Duration(crawler.DefaultIdleConnTimeout),
+
+		CollapseIndex: true,
+		LowercaseHost: true,
+		StripComments: true,
+
+		RespectCrawlDelay: true,
+		CrawlOrder:        harvester.CrawlOrderBFS,
+		Scope:             harvester.ScopeParent,
+
+		MinContentChars: 50,
+		ExcerptChars:    300,
+
+		BoilerplateThreshold: 0.5,
+
+		RetryFailed:  true,
+		RetryTimeout: Duration(3 * crawler.DefaultRequestTimeout),
+
+		SaveEveryNPages: storage.DefaultSaveEveryNPages,
+
+		CacheTTL: Duration(24 * time.Hour),
+	}
+}
+
+// ApplyPolitenessProfile sets the delay, concurrency, retry, User-Agent and robots options of a profile.
+// It is meant to be applied to the defaults, so a config file and flags can still override single options.
+func (c *Config) ApplyPolitenessProfile(name string) error {
+	switch name {
+	case "":
+	case ProfileAggressive:
+		c.Concurrency = 16
+		c.ConcurrencyPerHost = 0
+		c.Delay = 0
+		c.DelayJitter = 0
+		c.RespectCrawlDelay = false
+		c.RespectRobotsTxt = false
+		c.RetryFailed = false
+	case ProfileBalanced:
+		c.Concurrency = 4
+		c.ConcurrencyPerHost = 2
+		c.Delay = Duration(500 * time.Millisecond)
+		c.DelayJitter = Duration(200 * time.Millisecond)
+		c.RespectCrawlDelay = true
+		c.RetryFailed = true
+	case ProfilePolite:
+		c.Concurrency = 1
+		c.ConcurrencyPerHost = 1
+		c.Delay = Duration(2 * time.Second)
+		c.DelayJitter = Duration(500 * time.Millisecond)
+		c.RespectCrawlDelay = true
+		c.RespectRobotsTxt = true
+		c.RespectRobotsMeta = true
+		c.RespectNofollow = true
+		c.RetryFailed = true
+		c.UserAgents = []string{PoliteUserAgent}
+	default:
+		return fmt.Errorf("unknown politeness profile %q (use %s, %s or %s)", name, ProfileAggressive, ProfileBalanced, ProfilePolite)
+	}
+	c.PolitenessProfile = name
+	return nil
+}
+
+// Load reads a YAML (.yaml, .yml) or JSON (.json) config file on top of the defaults
+func Load(path string) (*Config, error) {
+	return LoadOnto(path, Default())
+}
+
+// LoadOnto reads a config file on top of cfg, e.g. defaults with a politeness profile applied, and returns cfg
+func LoadOnto(path string, cfg *Config) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".json":
+		err = json.Unmarshal(data, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s (use .yaml, .yml or .json)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	cfg.ConfigFile = path
+	return cfg, nil
+}
+
+// Validate checks option values that can be verified before crawling
+func (c *Config) Validate() error {
+	if c.Format != FormatXML && c.Format != FormatSingleMarkdown && c.Format != FormatJSONL {
+		return fmt.Errorf("unknown output format %q (use %s, %s or %s)", c.Format, FormatXML, FormatSingleMarkdown, FormatJSONL)
+	}
+
+	if c.LogFormat != LogFormatText && c.LogFormat != LogFormatJSON {
+		return fmt.Errorf("unknown log format %q (use %s or %s)", c.LogFormat, LogFormatText, LogFormatJSON)
+	}
+
+	if c.BaseURL != "" {
+		if _, err := node.ParseURL(c.BaseURL); err != nil {
+			return fmt.Errorf("base URL: %v", err)
+		}
+	}
+
+	for _, seedURL := range c.URLs {
+		if _, err := node.ParseURL(seedURL); err != nil {
+			return fmt.Errorf("seed URL: %v", err)
+		}
+	}
+
+	if c.Output != "" && c.OutputDir != "" {
+		return fmt.Errorf("use either an output file or an output directory, not both")
+	}
+
+	for _, name := range []string{c.XMLRootElement, c.XMLPageElement} {
+		if name != "" && !xmlNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid XML element name %q", name)
+		}
+	}
+
+	if _, err := c.SinceTime(); err != nil {
+		return err
+	}
+
+	if c.Render != RenderHTTP && c.Render != RenderJS {
+		return fmt.Errorf("unknown render backend %q (use %s or %s)", c.Render, RenderHTTP, RenderJS)
+	}
+
+	if c.CacheDir != "" && c.Render != RenderHTTP {
+		return fmt.Errorf("the page cache only works with -render %s", RenderHTTP)
+	}
+
+	if c.CrawlOrder != harvester.CrawlOrderBFS && c.CrawlOrder != harvester.CrawlOrderDFS {
+		return fmt.Errorf("unknown crawl order %q (use %s or %s)", c.CrawlOrder, harvester.CrawlOrderBFS, harvester.CrawlOrderDFS)
+	}
+
+	if c.BoilerplateThreshold <= 0 || c.BoilerplateThreshold >= 1 {
+		return fmt.Errorf("boilerplate threshold must be between 0 and 1, got %v", c.BoilerplateThreshold)
+	}
+
+	switch c.PolitenessProfile {
+	case "", ProfileAggressive, ProfileBalanced, ProfilePolite:
+	default:
+		return fmt.Errorf("unknown politeness profile %q (use %s, %s or %s)", c.PolitenessProfile, ProfileAggressive, ProfileBalanced, ProfilePolite)
+	}
+
+	if c.Scope != harvester.ScopeParent && c.Scope != harvester.ScopeSubtree {
+		return fmt.Errorf("unknown scope %q (use %s or %s)", c.Scope, harvester.ScopeParent, harvester.ScopeSubtree)
+	}
+
+	if c.DedupeBoilerplate && c.Format == FormatJSONL {
+		return fmt.Errorf("boilerplate can't be removed from %s output, which is written as pages are fetched", FormatJSONL)
+	}
+
+	if c.LocalLinks && c.Format != FormatSingleMarkdown {
+		return fmt.Errorf("local links are only supported with the %s format", FormatSingleMarkdown)
+	}
+
+	if c.DumpRaw && c.Format != FormatXML {
+		return fmt.Errorf("raw HTML can only be stored with the %s format", FormatXML)
+	}
+
+	if c.ExportSitemap != "" && c.Format != FormatXML {
+		return fmt.Errorf("a sitemap can only be exported with the %s format", FormatXML)
+	}
+
+	if c.ContentSelector != "" {
+		if _, err := extractor.ParseSelector(c.ContentSelector); err != nil {
+			return fmt.Errorf("content selector: %v", err)
+		}
+	}
+
+	for _, strategy := range c.ExtractionStrategies {
+		if !extractor.IsStrategy(strategy) {
+			return fmt.Errorf("unknown extraction strategy %q (use %s)", strategy, strings.Join(extractor.KnownStrategies, ", "))
+		}
+	}
+
+	for _, selector := range c.StripSelectors {
+		if _, err := extractor.ParseSelector(selector); err != nil {
+			return fmt.Errorf("strip selector: %v", err)
+		}
+	}
+
+	for _, pattern := range c.ExcludeText {
+		if _, err := extractor.CompileTextPattern(pattern); err != nil {
+			return fmt.Errorf("exclude text: %v", err)
+		}
+	}
+
+	for pattern, proxy := range c.ProxyRules {
+		if proxy == "" {
+			continue
+		}
+		if u, err := url.Parse(proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("proxy rule %q: invalid proxy URL %q", pattern, proxy)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestEffectiveMaxPages(t *testing.T) {
+	tests := []struct {
+		maxDepth, maxPages int
+		want               int
+	}{
+		{0, 0, DefaultUnlimitedMaxPages}, // Unlimited depth is guarded
+		{-1, 0, DefaultUnlimitedMaxPages},
+		{0, 50, 50},
+		{2, 0, 0}, // Limited depth needs no page limit
+		{2, 50, 50},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.MaxDepth, cfg.MaxPages = tt.maxDepth, tt.maxPages
+		if got := cfg.EffectiveMaxPages(); got != tt.want {
+			t.Errorf("EffectiveMaxPages() with max depth %d and max pages %d = %d, want %d", tt.maxDepth, tt.maxPages, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PageCache keeps fetched pages in a directory, keyed by URL, so later runs can parse them again without
+// fetching them, e.g. while tuning extraction settings. Only pages fetched with status 200 are stored.
+type PageCache struct {
+	Dir     string        // Directory holding one JSON file per URL
+	TTL     time.Duration // Age after which a cached page is fetched again (0 means cached pages don't expire)
+	Refresh bool          // Ignore cached pages, fetching every page again and replacing its cached copy
+}
+
+// CachedResponse is a response stored by PageCache
+type CachedResponse struct {
+	URL       string      `json:"url"` // Final URL, after redirects
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"` // Body as received, before decoding
+	FetchedAt time.Time   `json:"fetchedAt"`
+}
+
+// NewPageCache creates a cache in dir, creating the directory if needed
+func NewPageCache(dir string, ttl time.Duration) (*PageCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	return &PageCache{Dir: dir, TTL: ttl}, nil
+}
+
+// Get returns the cached response for a URL, unless there is none, it expired or Refresh is set
+func (pc *PageCache) Get(urlStr string) (*CachedResponse, bool) {
+	if pc.Refresh {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(pc.path(urlStr))
+	if err != nil {
+		return nil, false
+	}
+	var cached CachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	if pc.TTL > 0 && time.Since(cached.FetchedAt) > pc.TTL {
+		return nil, false
+	}
+	return &cached, true
+}
+
+// Put stores the response fetched for a URL, replacing an earlier one
+func (pc *PageCache) Put(urlStr string, response *CachedResponse) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode cached page: %v", err)
+	}
+
+	// Write to a temporary file first, so concurrent fetches and interrupted runs never leave a partial entry
+	path := pc.path(urlStr)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cached page: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cached page: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cached page: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cached page: %v", err)
+	}
+	return nil
+}
+
+// path returns the file of a URL: a hash of the URL, in a subdirectory named after its first two characters
+func (pc *PageCache) path(urlStr string) string {
+	sum := sha256.Sum256([]byte(urlStr))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(pc.Dir, name[:2], name+".json")
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// maxReplacementRatio is the share of undecodable characters above which a page's declared charset is considered wrong
+const maxReplacementRatio = 0.001
+
+// decodeBody decodes an HTML response body to UTF-8.
+// The charset is taken from the Content-Type header, a byte order mark or a <meta> tag, in that order.
+// If decoding with it yields too many replacement characters (U+FFFD), the charset is detected from the
+// body alone, falling back to windows-1252 (a superset of Latin-1) like browsers do.
+// It returns the decoded body, the charset used and, if that charset replaced the declared one, the declared charset.
+func decodeBody(data []byte, contentType string) (decoded []byte, used string, declared string) {
+	enc, name, _ := charset.DetermineEncoding(data, contentType)
+	decoded = decodeWith(enc, data)
+
+	ratio := replacementRatio(decoded)
+	if ratio <= maxReplacementRatio {
+		return decoded, name, ""
+	}
+
+	fallback, fallbackName, _ := charset.DetermineEncoding(data, "")
+	if fallbackName == name {
+		fallback, fallbackName = charmap.Windows1252, "windows-1252"
+	}
+	if fallbackName == name {
+		return decoded, name, ""
+	}
+
+	fallbackDecoded := decodeWith(fallback, data)
+	if replacementRatio(fallbackDecoded) >= ratio {
+		return decoded, name, ""
+	}
+	return fallbackDecoded, fallbackName, name
+}
+
+// decodeWith decodes data with an encoding, leaving it unchanged if it cannot be decoded
+func decodeWith(enc encoding.Encoding, data []byte) []byte {
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return data
+	}
+	return decoded
+}
+
+// replacementRatio returns the share of replacement characters (U+FFFD) among the characters of UTF-8 text
+func replacementRatio(text []byte) float64 {
+	total := utf8.RuneCount(text)
+	if total == 0 {
+		return 0
+	}
+	return float64(bytes.Count(text, []byte(string(utf8.RuneError)))) / float64(total)
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// latin1Page is a French page encoded in ISO-8859-1
+var latin1Page, _ = charmap.ISO8859_1.NewEncoder().Bytes([]byte(
+	"<html><head><title>Café</title></head><body><p>Déjà vu à l'été, garçon. Voilà où ça mène.</p></body></html>"))
+
+func TestDecodeBodyMislabeledLatin1(t *testing.T) {
+	decoded, used, declared := decodeBody(latin1Page, "text/html; charset=utf-8")
+
+	if !strings.Contains(string(decoded), "Déjà vu à l'été, garçon.") {
+		t.Errorf("decoded body = %q", decoded)
+	}
+	if used != "windows-1252" || declared != "utf-8" {
+		t.Errorf("decoded with %q instead of %q, want windows-1252 instead of utf-8", used, declared)
+	}
+}
+
+func TestDecodeBodyCorrectLabels(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		charset     string
+	}{
+		{"utf-8", []byte("<p>Déjà vu</p>"), "text/html; charset=utf-8", "utf-8"},
+		{"latin-1 header", latin1Page, "text/html; charset=iso-8859-1", "windows-1252"},
+		{"latin-1 meta", append([]byte(`<meta charset="iso-8859-1">`), latin1Page...), "text/html", "windows-1252"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, used, declared := decodeBody(tt.body, tt.contentType)
+			if used != tt.charset || declared != "" {
+				t.Errorf("decoded with %q (declared %q), want %q", used, declared, tt.charset)
+			}
+			if !strings.Contains(string(decoded), "Déjà vu") {
+				t.Errorf("decoded body = %q", decoded)
+			}
+		})
+	}
+}
+
+func TestFetchMislabeledLatin1(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(latin1Page)
+	}))
+	defer server.Close()
+
+	page, err := NewCrawler().Fetch(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.DeclaredCharset != "utf-8" || page.Charset != "windows-1252" {
+		t.Errorf("Charset = %q, DeclaredCharset = %q; want windows-1252 and utf-8", page.Charset, page.DeclaredCharset)
+	}
+	if title := NewCrawler().ExtractTitle(page.Doc); title != "Café" {
+		t.Errorf("title = %q, want Café", title)
+	}
+}
@@ -1,9 +1,22 @@
 package crawler
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/html"
@@ -12,69 +25,606 @@ import (
 // Crawler handles web crawling logic
 type Crawler struct {
 	UserAgent      string        // Simulated browser information
-	RequestTimeout time.Duration // Request timeout
+	UserAgents     []string      // If non-empty, requests rotate through these instead of UserAgent
+	RequestTimeout time.Duration // Deadline of a single request, including reading the response (0 means none)
 	Client         *http.Client  // HTTP client
+	AllowedSchemes []string      // URL schemes kept by ExtractLinks (mailto:, javascript: etc. are dropped)
+	MaxRedirects   int           // Maximum number of redirects followed per request
+
+	// Media types (e.g. "text/html") FetchPage parses; other responses fail with ErrSkippedContentType.
+	// Empty accepts every content type.
+	AcceptContentTypes []string
+
+	// Connection pooling; call ResetTransport after changing these
+	MaxIdleConnsPerHost int           // Idle connections kept open per host
+	IdleConnTimeout     time.Duration // How long an idle connection is kept open
+	DisableKeepAlives   bool          // Open a new connection for every request
+
+	// TLS; call ResetTransport after changing these
+	InsecureSkipVerify bool           // Accept any server certificate, e.g. a self-signed one; insecure, prefer RootCAs
+	RootCAs            *x509.CertPool // Certificate authorities to trust; nil means the system's, see LoadCACert
+
+	// Proxy per request host, keyed by host pattern ("docs.example.org", "*.internal" or "*").
+	// An empty proxy URL connects directly. Without rules, the environment proxy is used. Call ResetTransport after changing these.
+	ProxyRules map[string]string
+
+	// Rate limiting: requests start at least RequestDelay apart, give or take a random RequestDelayJitter
+	RequestDelay       time.Duration // Base delay between the starts of two requests (0 means none)
+	RequestDelayJitter time.Duration // Maximum random deviation from RequestDelay in either direction
+
+	// Honor the Crawl-delay of each host's robots.txt, fetched before the first request to the host.
+	// A Crawl-delay longer than RequestDelay separates the requests to that host instead.
+	RespectCrawlDelay bool
+
+	// Refuse URLs the robots.txt of their host disallows for the user agent; such fetches fail with ErrRobotsDisallowed
+	RespectRobotsTxt bool
+
+	// Maximum requests in flight to a single host at the same time (0 means no limit)
+	MaxConcurrencyPerHost int
+
+	// If set, Fetch serves pages from the cache instead of the network, and stores the pages it fetches
+	Cache *PageCache
+
+	hostSlotsMu sync.Mutex
+	hostSlots   map[string]chan struct{} // Semaphore per host, for MaxConcurrencyPerHost
+
+	robotsMu     sync.Mutex
+	robots       map[string]*hostRobots // robots.txt rules per host, for RespectCrawlDelay and RespectRobotsTxt
+	hostRequests map[string]time.Time   // When the previous request to each host was reserved to start, guarded by delayMu
+
+	nextAgent   atomic.Uint64 // Index of the next entry of UserAgents
+	delayMu     sync.Mutex    // Guards reserving the start of a request
+	lastRequest time.Time     // When the previous request was reserved to start
+}
+
+// DefaultRequestTimeout is the deadline of a single request made by a new Crawler
+const DefaultRequestTimeout = 10 * time.Second
+
+// DefaultMaxRedirects is the number of redirects a new Crawler follows per request
+const DefaultMaxRedirects = 10
+
+// ErrSkippedContentType is returned by FetchPage for responses whose content type is not accepted
+var ErrSkippedContentType = errors.New("skipped content type")
+
+// ErrTooLarge is returned by FetchAsset for assets larger than the size limit
+var ErrTooLarge = errors.New("exceeds size limit")
+
+// ErrParse is returned for responses that can't be parsed as HTML
+var ErrParse = errors.New("failed to parse HTML")
+
+// ErrRobotsDisallowed is returned for URLs the host's robots.txt disallows, when RespectRobotsTxt is set
+var ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+// StatusError reports a response with a status other than 200 OK
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received non-200 response: %d %s", e.StatusCode, e.Status)
+}
+
+// RedirectError reports a request whose redirects were not followed to the end
+type RedirectError struct {
+	URL       string // URL the request started from
+	Location  string // Redirect target that was refused
+	Redirects int    // Number of redirects followed before giving up
+	Loop      bool   // Whether the target had already been visited by this request
 }
 
+func (e *RedirectError) Error() string {
+	if e.Loop {
+		return fmt.Sprintf("redirect loop: %s redirects back to %s", e.URL, e.Location)
+	}
+	return fmt.Sprintf("too many redirects: %s stopped after %d redirects", e.URL, e.Redirects)
+}
+
+// Connection pooling defaults, tuned for crawling many pages of a single host
+const (
+	DefaultMaxIdleConnsPerHost = 16
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// DefaultAllowedSchemes are the URL schemes followed by a new Crawler
+var DefaultAllowedSchemes = []string{"http", "https"}
+
 // NewCrawler creates a new Crawler instance
 func NewCrawler() *Crawler {
-	return &Crawler{
-		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-		RequestTimeout: 10 * time.Second,
-		Client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+	c := &Crawler{
+		UserAgent:           "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+		RequestTimeout:      DefaultRequestTimeout,
+		AllowedSchemes:      append([]string(nil), DefaultAllowedSchemes...),
+		MaxRedirects:        DefaultMaxRedirects,
+		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     DefaultIdleConnTimeout,
+		Client:              &http.Client{}, // Requests are limited by RequestTimeout instead of a client-wide timeout
+	}
+	// Cookies set by a site (e.g. a login session) are sent with later requests; cookiejar.New never fails without options
+	c.Client.Jar, _ = cookiejar.New(nil)
+	c.Client.CheckRedirect = c.checkRedirect
+	c.ResetTransport()
+	return c
+}
+
+// checkRedirect stops following redirects after MaxRedirects or when a location repeats
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return &RedirectError{URL: via[0].URL.String(), Location: req.URL.String(), Redirects: len(via) - 1, Loop: true}
+		}
+	}
+
+	if len(via) > c.MaxRedirects {
+		return &RedirectError{URL: via[0].URL.String(), Location: req.URL.String(), Redirects: len(via) - 1}
+	}
+
+	return nil
+}
+
+// ResetTransport replaces the client's transport with one built from the connection pooling settings
+func (c *Crawler) ResetTransport() {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 0 // No global limit; MaxIdleConnsPerHost bounds each host
+	transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = c.IdleConnTimeout
+	transport.DisableKeepAlives = c.DisableKeepAlives
+	if len(c.ProxyRules) > 0 {
+		transport.Proxy = c.proxyFunc
+	}
+	if c.InsecureSkipVerify || c.RootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify, RootCAs: c.RootCAs}
+	}
+	c.Client.Transport = transport
+}
+
+// LoadCACert returns the system's certificate authorities plus those of a PEM file, such as the CA of an internal server
+func LoadCACert(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// proxyFunc returns the proxy of the rule matching a request's host, or nil to connect directly
+func (c *Crawler) proxyFunc(req *http.Request) (*url.URL, error) {
+	proxy, ok := MatchProxyRule(c.ProxyRules, req.URL.Hostname())
+	if !ok || proxy == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %v", proxy, err)
+	}
+	return proxyURL, nil
+}
+
+// MatchProxyRule returns the proxy of the most specific rule matching a host.
+// An exact host wins over wildcard patterns, a longer "*.suffix" over a shorter one, and "*" matches any host.
+func MatchProxyRule(rules map[string]string, host string) (string, bool) {
+	host = strings.ToLower(host)
+	if proxy, ok := rules[host]; ok {
+		return proxy, true
+	}
+
+	best := ""
+	for pattern := range rules {
+		suffix, ok := strings.CutPrefix(strings.ToLower(pattern), "*")
+		if !ok || !strings.HasSuffix(host, suffix) || len(pattern) <= len(best) {
+			continue
+		}
+		if suffix == "" || strings.HasPrefix(suffix, ".") {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return rules[best], true
+}
+
+// userAgent returns the User-Agent of the next request, rotating round-robin through UserAgents if set
+func (c *Crawler) userAgent() string {
+	if len(c.UserAgents) == 0 {
+		return c.UserAgent
+	}
+	i := c.nextAgent.Add(1) - 1
+	return c.UserAgents[i%uint64(len(c.UserAgents))]
+}
+
+// ReadUserAgents reads User-Agent strings from a file, one per line.
+// Blank lines and lines starting with # are ignored.
+func ReadUserAgents(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user agent file: %v", err)
+	}
+
+	var agents []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			agents = append(agents, line)
+		}
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no user agents found in %s", path)
+	}
+
+	return agents, nil
+}
+
+// LoginForm submits a login form before crawling. The fields are POSTed form-encoded to loginURL,
+// and the session cookies of the response are kept in the client's cookie jar for later requests.
+func (c *Crawler) LoginForm(loginURL string, fields map[string]string) error {
+	if c.Client.Jar == nil {
+		return fmt.Errorf("login requires a cookie jar on the HTTP client")
+	}
+
+	form := url.Values{}
+	for name, value := range fields {
+		form.Set(name, value)
+	}
+
+	req, cancel, err := c.newRequest("POST", loginURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create login request: %v", err)
+	}
+	defer cancel()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", c.userAgent())
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to submit login form: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login failed: %w", &StatusError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+
+	return nil
+}
+
+// newRequest creates a request limited by RequestTimeout, waiting for the request delay and a free slot of the host.
+// The returned cancel function must be called once the response body has been read.
+func (c *Crawler) newRequest(method, urlStr string, body io.Reader) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Wait for a free slot of the host before the request delay, so the delay separates the requests actually sent
+	release := c.acquireHost(req.URL.Host)
+	c.wait(req.URL)
+
+	if c.RequestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.RequestTimeout)
+		req = req.WithContext(ctx)
 	}
+	return req, func() { cancel(); release() }, nil
+}
+
+// acquireHost waits until fewer than MaxConcurrencyPerHost requests to a host are in flight
+// and returns the function ending the caller's request
+func (c *Crawler) acquireHost(host string) func() {
+	if c.MaxConcurrencyPerHost <= 0 {
+		return func() {}
+	}
+
+	c.hostSlotsMu.Lock()
+	if c.hostSlots == nil {
+		c.hostSlots = make(map[string]chan struct{})
+	}
+	slots, ok := c.hostSlots[host]
+	if !ok {
+		slots = make(chan struct{}, c.MaxConcurrencyPerHost)
+		c.hostSlots[host] = slots
+	}
+	c.hostSlotsMu.Unlock()
+
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// wait blocks until the request delay since the previous request has passed,
+// or the host's crawl delay since the previous request to the host if that is longer.
+// The start of the request is reserved under delayMu, so waiting for one host does not hold up requests to the others.
+func (c *Crawler) wait(u *url.URL) {
+	hostDelay := c.crawlDelay(u)
+
+	c.delayMu.Lock()
+	start := time.Now()
+	delay := c.nextDelay()
+	if delay > 0 && !c.lastRequest.IsZero() {
+		start = later(start, c.lastRequest.Add(delay))
+	}
+	if last, ok := c.hostRequests[u.Host]; ok && hostDelay > delay {
+		start = later(start, last.Add(hostDelay))
+	}
+	c.lastRequest = start
+	if c.hostRequests == nil {
+		c.hostRequests = make(map[string]time.Time)
+	}
+	c.hostRequests[u.Host] = start
+	c.delayMu.Unlock()
+
+	time.Sleep(time.Until(start))
+}
+
+// later returns the later of two times
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// nextDelay returns RequestDelay shifted by a random amount within ±RequestDelayJitter, never negative
+func (c *Crawler) nextDelay() time.Duration {
+	delay := c.RequestDelay
+	if c.RequestDelayJitter > 0 {
+		delay += rand.N(2*c.RequestDelayJitter+1) - c.RequestDelayJitter
+	}
+	return max(delay, 0)
+}
+
+// Page is a fetched and parsed HTML page
+type Page struct {
+	URL    string      // Final URL, after redirects
+	Header http.Header // Response headers
+	Doc    *html.Node  // Parsed document
+	Body   []byte      // Body the document was parsed from, decoded to UTF-8
+	Size   int64       // Number of body bytes read
+
+	Charset         string // Character encoding the body was decoded with
+	DeclaredCharset string // Charset declared by the page, if it was wrong and Charset was detected instead
+
+	Cached bool // Whether the page was served from the Cache instead of the network
 }
 
 // FetchPage fetches HTML content of a single page
 func (c *Crawler) FetchPage(urlStr string) (*html.Node, error) {
-	req, err := http.NewRequest("GET", urlStr, nil)
+	page, err := c.Fetch(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return page.Doc, nil
+}
+
+// Fetch fetches a single page like FetchPage, also returning the response headers.
+// With a Cache, a cached page is parsed instead, without waiting for the request delay.
+func (c *Crawler) Fetch(urlStr string) (*Page, error) {
+	if c.Cache != nil {
+		if cached, ok := c.Cache.Get(urlStr); ok {
+			if contentType := cached.Header.Get("Content-Type"); !c.isAcceptedContentType(contentType) {
+				return nil, fmt.Errorf("%w: %s", ErrSkippedContentType, contentType)
+			}
+			page, err := newPage(cached.URL, cached.Header, cached.Body)
+			if err != nil {
+				return nil, err
+			}
+			page.Cached = true
+			return page, nil
+		}
+	}
+
+	if err := c.checkRobots(urlStr); err != nil {
+		return nil, err
+	}
+	req, cancel, err := c.newRequest("GET", urlStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
 	}
+	defer cancel()
 
-	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("User-Agent", c.userAgent())
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch the URL: %v", err)
+		var redirectErr *RedirectError
+		if errors.As(err, &redirectErr) {
+			return nil, redirectErr
+		}
+		return nil, fmt.Errorf("failed to fetch the URL: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 response: %d %s", resp.StatusCode, resp.Status)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !c.isAcceptedContentType(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrSkippedContentType, contentType)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response: %w", err)
+	}
+
+	page, err := newPage(resp.Request.URL.String(), resp.Header, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// A page that can't be cached is still returned; the next run fetches it again
+	if c.Cache != nil {
+		c.Cache.Put(urlStr, &CachedResponse{URL: page.URL, Header: resp.Header, Body: data, FetchedAt: time.Now()})
 	}
 
-	doc, err := html.Parse(resp.Body)
+	return page, nil
+}
+
+// newPage decodes and parses the body of a response
+func newPage(finalURL string, header http.Header, data []byte) (*Page, error) {
+	decoded, used, declared := decodeBody(data, header.Get("Content-Type"))
+	doc, err := html.Parse(bytes.NewReader(decoded))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrParse, err)
 	}
 
-	return doc, nil
+	return &Page{
+		URL:             finalURL,
+		Header:          header,
+		Doc:             doc,
+		Body:            decoded,
+		Size:            int64(len(data)),
+		Charset:         used,
+		DeclaredCharset: declared,
+	}, nil
 }
 
-// ExtractLinks extracts all links from HTML
+// FetchAsset downloads a binary asset such as an image, refusing assets larger than maxSize bytes.
+// It returns the asset data and its content type.
+func (c *Crawler) FetchAsset(urlStr string, maxSize int64) ([]byte, string, error) {
+	if err := c.checkRobots(urlStr); err != nil {
+		return nil, "", err
+	}
+	req, cancel, err := c.newRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	defer cancel()
+
+	req.Header.Set("User-Agent", c.userAgent())
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch the asset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, "", fmt.Errorf("%w: asset size %d, limit %d bytes", ErrTooLarge, resp.ContentLength, maxSize)
+	}
+
+	reader := io.Reader(resp.Body)
+	if maxSize > 0 {
+		reader = io.LimitReader(resp.Body, maxSize+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the asset: %w", err)
+	}
+
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, "", fmt.Errorf("%w: asset larger than %d bytes", ErrTooLarge, maxSize)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	return data, contentType, nil
+}
+
+// Head returns the status code of a URL without downloading its body.
+// Redirects are not followed, so they report their 3xx status. Servers that don't allow HEAD are asked with GET instead.
+func (c *Crawler) Head(urlStr string) (int, error) {
+	status, err := c.status("HEAD", urlStr)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		return c.status("GET", urlStr)
+	}
+	return status, err
+}
+
+// status sends a request without following redirects and returns the response status code
+func (c *Crawler) status(method, urlStr string) (int, error) {
+	if err := c.checkRobots(urlStr); err != nil {
+		return 0, err
+	}
+	req, cancel, err := c.newRequest(method, urlStr, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	defer cancel()
+
+	req.Header.Set("User-Agent", c.userAgent())
+
+	client := *c.Client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch the URL: %w", err)
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// Link is a link found on a page
+type Link struct {
+	URL  string // Absolute URL
+	Rel  string // Value of the rel attribute, e.g. "nofollow"
+	Text string // Visible anchor text, with whitespace collapsed
+}
+
+// IsNofollow reports whether the link is marked rel="nofollow"
+func (l Link) IsNofollow() bool {
+	for _, rel := range strings.Fields(strings.ToLower(l.Rel)) {
+		if rel == "nofollow" {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractLinks extracts all links from HTML.
+// Links are resolved against the base URL and returned once each, in first-seen order;
+// links differing only in their fragment count as the same link. Links with a scheme
+// outside AllowedSchemes, such as mailto: or javascript:, are skipped.
 func (c *Crawler) ExtractLinks(doc *html.Node, baseURLStr string) ([]string, error) {
+	detailed, err := c.ExtractLinksDetailed(doc, baseURLStr)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]string, 0, len(detailed))
+	for _, link := range detailed {
+		links = append(links, link.URL)
+	}
+	return links, nil
+}
+
+// ExtractLinksDetailed extracts links like ExtractLinks, along with their rel attribute and anchor text
+func (c *Crawler) ExtractLinksDetailed(doc *html.Node, baseURLStr string) ([]Link, error) {
 	baseURL, err := url.Parse(baseURLStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base URL: %v", err)
 	}
 
-	var links []string
+	var links []Link
+	seen := make(map[string]bool)
 	var extractFunc func(*html.Node)
 
 	extractFunc = func(n *html.Node) {
 		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, attr := range n.Attr {
-				if attr.Key == "href" {
-					hrefURL, err := url.Parse(attr.Val)
-					if err != nil {
-						continue
-					}
-					fullURL := baseURL.ResolveReference(hrefURL)
-					links = append(links, fullURL.String())
-					break
+			if link, ok := c.parseLink(n, baseURL); ok {
+				key, _ := url.Parse(link.URL)
+				key.Fragment = ""
+				if !seen[key.String()] {
+					seen[key.String()] = true
+					links = append(links, link)
 				}
 			}
 		}
@@ -88,6 +638,74 @@ func (c *Crawler) ExtractLinks(doc *html.Node, baseURLStr string) ([]string, err
 	return links, nil
 }
 
+// parseLink reads the link of an <a> element; it reports false for anchors without a followable href
+func (c *Crawler) parseLink(a *html.Node, baseURL *url.URL) (Link, bool) {
+	var link Link
+	hasHref := false
+	for _, attr := range a.Attr {
+		switch attr.Key {
+		case "href":
+			hrefURL, err := url.Parse(attr.Val)
+			if err != nil {
+				return Link{}, false
+			}
+			fullURL := baseURL.ResolveReference(hrefURL)
+			if !c.isAllowedScheme(fullURL.Scheme) {
+				return Link{}, false
+			}
+			link.URL = fullURL.String()
+			hasHref = true
+		case "rel":
+			link.Rel = attr.Val
+		}
+	}
+
+	link.Text = strings.Join(strings.Fields(anchorText(a)), " ")
+	return link, hasHref
+}
+
+// anchorText returns the text of a node and its descendants
+func anchorText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(anchorText(child))
+	}
+	return sb.String()
+}
+
+// isAcceptedContentType checks a Content-Type header against AcceptContentTypes, ignoring parameters such as charset
+func (c *Crawler) isAcceptedContentType(contentType string) bool {
+	if len(c.AcceptContentTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, accepted := range c.AcceptContentTypes {
+		if strings.EqualFold(mediaType, accepted) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowedScheme checks whether links with the given scheme are extracted
+func (c *Crawler) isAllowedScheme(scheme string) bool {
+	for _, allowed := range c.AllowedSchemes {
+		if strings.EqualFold(scheme, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsSameDomain checks if two URLs belong to the same domain
 func (c *Crawler) IsSameDomain(url1, url2 string) bool {
 	u1, err := url.Parse(url1)
@@ -0,0 +1,264 @@
+package crawler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// extractLinks returns the links of an HTML snippet as a new Crawler extracts them
+func extractLinks(t *testing.T, body string, baseURL string) []string {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	links, err := NewCrawler().ExtractLinks(doc, baseURL)
+	if err != nil {
+		t.Fatalf("ExtractLinks: %v", err)
+	}
+	return links
+}
+
+func TestExtractLinksDeduplicates(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "same link three times",
+			body: `<a href="/guide">Guide</a><a href="/guide">Guide</a><a href="/guide">Again</a>`,
+			want: []string{"https://example.org/guide"},
+		},
+		{
+			name: "relative and absolute forms",
+			body: `<a href="intro">Intro</a><a href="https://example.org/docs/intro">Intro</a><a href="/docs/intro">Intro</a>`,
+			want: []string{"https://example.org/docs/intro"},
+		},
+		{
+			name: "fragments of one page",
+			body: `<a href="/api#get">GET</a><a href="/api#post">POST</a><a href="/api">API</a>`,
+			want: []string{"https://example.org/api#get"},
+		},
+		{
+			name: "first-seen order",
+			body: `<a href="/b">B</a><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a>`,
+			want: []string{"https://example.org/b", "https://example.org/a", "https://example.org/c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractLinks(t, tt.body, "https://example.org/docs/"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractLinks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinksSchemes(t *testing.T) {
+	tests := []struct {
+		name string
+		href string
+		want []string
+	}{
+		{"mailto", "mailto:docs@example.org", nil},
+		{"javascript", "javascript:void(0)", nil},
+		{"tel", "tel:+1-555-0100", nil},
+		{"data", "data:text/html,<p>hi</p>", nil},
+		{"http", "http://example.org/page", []string{"http://example.org/page"}},
+		{"relative", "page", []string{"https://example.org/docs/page"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractLinks(t, `<a href="`+tt.href+`">Link</a>`, "https://example.org/docs/")
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("ExtractLinks(%q) = %v, want %v", tt.href, got, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkFetchConnectionReuse fetches pages of one host from parallel goroutines,
+// with the default connection pool and with a new connection for every request
+func BenchmarkFetchConnectionReuse(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body><p>Page</p></body></html>")
+	}))
+	defer server.Close()
+
+	for _, bm := range []struct {
+		name              string
+		disableKeepAlives bool
+	}{
+		{"keep-alive", false},
+		{"no-keep-alive", true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			c := NewCrawler()
+			c.DisableKeepAlives = bm.disableKeepAlives
+			c.ResetTransport()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := c.Fetch(server.URL + "/page"); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestFetchRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/self", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/self", http.StatusFound)
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+	mux.HandleFunc("/chain/", func(w http.ResponseWriter, r *http.Request) {
+		// Each redirect lengthens the path, so locations never repeat
+		n := len(r.URL.Path)
+		http.Redirect(w, r, r.URL.Path+strings.Repeat("x", n%3+1), http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path      string
+		loop      bool
+		redirects int
+	}{
+		{"/self", true, 0},
+		{"/a", true, 1},
+		{"/chain/", false, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			c := NewCrawler()
+			c.MaxRedirects = 3
+
+			_, err := c.Fetch(server.URL + tt.path)
+			var redirectErr *RedirectError
+			if !errors.As(err, &redirectErr) {
+				t.Fatalf("Fetch(%s) error = %v, want a *RedirectError", tt.path, err)
+			}
+			if redirectErr.Loop != tt.loop || redirectErr.Redirects != tt.redirects {
+				t.Errorf("RedirectError{Loop: %v, Redirects: %d}, want {Loop: %v, Redirects: %d}",
+					redirectErr.Loop, redirectErr.Redirects, tt.loop, tt.redirects)
+			}
+			if redirectErr.URL != server.URL+tt.path {
+				t.Errorf("RedirectError.URL = %s, want the requested URL", redirectErr.URL)
+			}
+		})
+	}
+}
+
+func TestExtractLinksDetailedAnchorText(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<nav>
+<a href="/guide" rel="nofollow"><span class="icon"></span><span>User <b>guide</b></span>
+  <span>(PDF)</span></a>
+<a href="/api"><img src="api.png" alt="API"></a>
+</nav>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := NewCrawler().ExtractLinksDetailed(doc, "https://example.org/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Link{
+		{URL: "https://example.org/guide", Rel: "nofollow", Text: "User guide (PDF)"},
+		{URL: "https://example.org/api"},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("ExtractLinksDetailed() = %+v, want %+v", links, want)
+	}
+}
+
+func TestMatchProxyRule(t *testing.T) {
+	rules := map[string]string{
+		"*":                  "http://proxy:3128",
+		"*.internal":         "socks5://gateway:1080",
+		"*.docs.internal":    "socks5://docs-gateway:1080",
+		"wiki.docs.internal": "",
+		"*ample.org":         "http://ignored:1", // Not a domain suffix
+		"public.example.org": "http://public-proxy:8080",
+	}
+	tests := []struct {
+		host  string
+		proxy string
+	}{
+		{"wiki.docs.internal", ""},                          // Exact host wins, even without a proxy
+		{"api.docs.internal", "socks5://docs-gateway:1080"}, // Longer suffix wins
+		{"build.internal", "socks5://gateway:1080"},         // Shorter suffix
+		{"Build.Internal", "socks5://gateway:1080"},         // Case-insensitive
+		{"internal", "http://proxy:3128"},                   // *.internal needs a subdomain
+		{"public.example.org", "http://public-proxy:8080"},  // Exact host
+		{"www.example.org", "http://proxy:3128"},            // Only * matches
+	}
+	for _, tt := range tests {
+		proxy, ok := MatchProxyRule(rules, tt.host)
+		if !ok || proxy != tt.proxy {
+			t.Errorf("MatchProxyRule(%q) = %q, %v; want %q", tt.host, proxy, ok, tt.proxy)
+		}
+	}
+
+	if _, ok := MatchProxyRule(map[string]string{"*.internal": "socks5://gateway:1080"}, "example.org"); ok {
+		t.Error("a host matching no rule has a proxy")
+	}
+}
+
+func TestMaxConcurrencyPerHost(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body><p>Page</p></body></html>")
+	}))
+	defer server.Close()
+
+	c := NewCrawler()
+	c.MaxConcurrencyPerHost = 2
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Fetch(server.URL + "/page" + strconv.Itoa(i)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak requests in flight = %d, want 2", got)
+	}
+}
@@ -0,0 +1,145 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/html"
+)
+
+// DefaultRenderWait is how long a new Renderer waits after a page has loaded for client-side rendering to finish
+const DefaultRenderWait = time.Second
+
+// Renderer fetches pages with a headless Chrome and returns the DOM after JavaScript has run,
+// so pages rendered on the client are harvested with their content.
+// Links, assets and HEAD checks are handled by the embedded Crawler over plain HTTP,
+// which also provides the request delay and the per-host concurrency limit.
+// Cookies of the Crawler (e.g. from LoginForm) are not shared with the browser.
+type Renderer struct {
+	*Crawler
+	ExecPath   string        // Chrome or Chromium executable; looked up in the usual locations when empty
+	RenderWait time.Duration // Wait after the page has loaded, for content fetched by scripts
+
+	startOnce     sync.Once
+	startErr      error
+	browserCtx    context.Context
+	cancelBrowser context.CancelFunc
+}
+
+// NewRenderer creates a Renderer using c for everything but fetching pages
+func NewRenderer(c *Crawler) *Renderer {
+	return &Renderer{Crawler: c, RenderWait: DefaultRenderWait}
+}
+
+// start launches the browser on first use
+func (r *Renderer) start() error {
+	r.startOnce.Do(func() {
+		opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.UserAgent(r.userAgent()))
+		if r.ExecPath != "" {
+			opts = append(opts, chromedp.ExecPath(r.ExecPath))
+		}
+		if r.InsecureSkipVerify {
+			opts = append(opts, chromedp.Flag("ignore-certificate-errors", true))
+		}
+		allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+		browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+		r.browserCtx = browserCtx
+		r.cancelBrowser = func() { cancelBrowser(); cancelAlloc() }
+
+		// Running without actions starts the browser
+		if err := chromedp.Run(browserCtx); err != nil {
+			r.startErr = fmt.Errorf("failed to start headless browser: %v", err)
+		}
+	})
+	return r.startErr
+}
+
+// Close shuts the browser down
+func (r *Renderer) Close() error {
+	if r.cancelBrowser != nil {
+		r.cancelBrowser()
+	}
+	return nil
+}
+
+// FetchPage renders a page and returns its DOM
+func (r *Renderer) FetchPage(urlStr string) (*html.Node, error) {
+	page, err := r.Fetch(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return page.Doc, nil
+}
+
+// Fetch renders a page in a new browser tab like FetchPage, also returning the response headers of the document
+func (r *Renderer) Fetch(urlStr string) (*Page, error) {
+	if err := r.start(); err != nil {
+		return nil, err
+	}
+
+	// Reuse the robots.txt rules, request delay and host limit of plain requests
+	if err := r.checkRobots(urlStr); err != nil {
+		return nil, err
+	}
+	req, cancel, err := r.newRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	defer cancel()
+
+	tabCtx, closeTab := chromedp.NewContext(r.browserCtx)
+	defer closeTab()
+	if r.RequestTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		tabCtx, cancelTimeout = context.WithTimeout(tabCtx, r.RequestTimeout)
+		defer cancelTimeout()
+	}
+
+	resp, err := chromedp.RunResponse(tabCtx, chromedp.Navigate(req.URL.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the URL: %w", err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("failed to render the URL: no response")
+	}
+	if resp.Status != http.StatusOK {
+		return nil, &StatusError{StatusCode: int(resp.Status), Status: fmt.Sprintf("%d %s", resp.Status, resp.StatusText)}
+	}
+	if !r.isAcceptedContentType(resp.MimeType) {
+		return nil, fmt.Errorf("%w: %s", ErrSkippedContentType, resp.MimeType)
+	}
+
+	var location, outerHTML string
+	err = chromedp.Run(tabCtx,
+		chromedp.Sleep(r.RenderWait),
+		chromedp.Location(&location),
+		chromedp.OuterHTML("html", &outerHTML, chromedp.ByQuery),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the rendered page: %w", err)
+	}
+
+	doc, err := html.Parse(strings.NewReader(outerHTML))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+
+	return &Page{URL: location, Header: responseHeader(resp.Headers), Doc: doc, Body: []byte(outerHTML), Size: int64(len(outerHTML))}, nil
+}
+
+// responseHeader converts the headers reported by the browser; repeated headers arrive joined by newlines
+func responseHeader(headers network.Headers) http.Header {
+	header := make(http.Header)
+	for key, value := range headers {
+		for _, line := range strings.Split(fmt.Sprint(value), "\n") {
+			header.Add(key, line)
+		}
+	}
+	return header
+}
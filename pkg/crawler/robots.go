@@ -0,0 +1,233 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRobotsSize is the number of bytes of a robots.txt file that are read
+const maxRobotsSize = 512 * 1024
+
+// RobotsRules are the rules of a robots.txt file that apply to one user agent
+type RobotsRules struct {
+	CrawlDelay    time.Duration // Crawl-delay of the user agent's group
+	HasCrawlDelay bool          // Whether a Crawl-delay was set
+	rules         []robotsRule
+}
+
+// robotsRule is an Allow or Disallow line; patterns may contain * and end with $
+type robotsRule struct {
+	pattern *regexp.Regexp
+	length  int // Length of the pattern as written, the longest matching rule wins
+	allow   bool
+}
+
+// ParseRobots returns the rules a robots.txt file sets for a user agent.
+// Groups naming a product token contained in userAgent take precedence over the "*" group; matching groups are merged.
+func ParseRobots(r io.Reader, userAgent string) *RobotsRules {
+	userAgent = strings.ToLower(userAgent)
+
+	var agents []string // User agents of the current group
+	inRules := false    // Whether the current group's rules have started
+	var generic, specific RobotsRules
+	var hasSpecific bool
+
+	// apply calls set with the rules of each kind of group the current group belongs to
+	apply := func(set func(rules *RobotsRules)) {
+		for _, agent := range agents {
+			switch {
+			case agent == "*":
+				set(&generic)
+			case agent != "" && strings.Contains(userAgent, agent):
+				set(&specific)
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// A user agent line after rules starts a new group
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agent := strings.ToLower(value)
+			agents = append(agents, agent)
+			hasSpecific = hasSpecific || (agent != "*" && agent != "" && strings.Contains(userAgent, agent))
+			continue
+		}
+		inRules = true
+
+		switch key {
+		case "crawl-delay":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			apply(func(rules *RobotsRules) {
+				rules.CrawlDelay = time.Duration(seconds * float64(time.Second))
+				rules.HasCrawlDelay = true
+			})
+		case "allow", "disallow":
+			// An empty Disallow allows everything
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{pattern: robotsPattern(value), length: len(value), allow: key == "allow"}
+			apply(func(rules *RobotsRules) { rules.rules = append(rules.rules, rule) })
+		}
+	}
+
+	if !hasSpecific {
+		return &generic
+	}
+	if !specific.HasCrawlDelay {
+		specific.CrawlDelay, specific.HasCrawlDelay = generic.CrawlDelay, generic.HasCrawlDelay
+	}
+	return &specific
+}
+
+// robotsPattern compiles a path pattern of robots.txt, where * matches any characters and a final $ anchors the end
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// Allowed reports whether a URL path, including its query, may be fetched.
+// The longest matching rule decides, Allow winning ties; paths no rule matches are allowed, and so is /robots.txt.
+func (r *RobotsRules) Allowed(path string) bool {
+	if r == nil || path == "/robots.txt" {
+		return true
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > longest || (rule.length == longest && rule.allow) {
+			allowed, longest = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
+
+// ParseCrawlDelay returns the Crawl-delay a robots.txt file sets for a user agent.
+// A group naming a product token contained in userAgent takes precedence over the "*" group.
+func ParseCrawlDelay(r io.Reader, userAgent string) (time.Duration, bool) {
+	rules := ParseRobots(r, userAgent)
+	return rules.CrawlDelay, rules.HasCrawlDelay
+}
+
+// crawlDelay returns the Crawl-delay of a host's robots.txt, fetching it on the first request to the host.
+// Hosts without a robots.txt or a Crawl-delay get 0.
+func (c *Crawler) crawlDelay(u *url.URL) time.Duration {
+	if !c.RespectCrawlDelay {
+		return 0
+	}
+	return c.robotsRules(u).CrawlDelay
+}
+
+// checkRobots returns an ErrRobotsDisallowed error for a URL its host's robots.txt disallows, when RespectRobotsTxt is set
+func (c *Crawler) checkRobots(urlStr string) error {
+	if !c.RespectRobotsTxt {
+		return nil
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil || u.Host == "" {
+		return nil // Reported by the request
+	}
+	if !c.robotsRules(u).Allowed(u.EscapedPath() + queryPart(u)) {
+		return fmt.Errorf("%w: %s", ErrRobotsDisallowed, urlStr)
+	}
+	return nil
+}
+
+// queryPart returns the query of a URL with its leading "?", or nothing
+func queryPart(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	return "?" + u.RawQuery
+}
+
+// hostRobots holds the robots.txt rules of one host, fetched once
+type hostRobots struct {
+	once  sync.Once
+	rules *RobotsRules
+}
+
+// robotsRules returns the rules of a host's robots.txt, fetching it on the first request to the host.
+// Hosts without a robots.txt get no rules. robotsMu only guards the map, so fetches for different hosts don't block each other.
+func (c *Crawler) robotsRules(u *url.URL) *RobotsRules {
+	c.robotsMu.Lock()
+	if c.robots == nil {
+		c.robots = make(map[string]*hostRobots)
+	}
+	entry, ok := c.robots[u.Host]
+	if !ok {
+		entry = &hostRobots{}
+		c.robots[u.Host] = entry
+	}
+	c.robotsMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules = c.fetchRobots(u.Scheme + "://" + u.Host + "/robots.txt")
+	})
+	return entry.rules
+}
+
+// fetchRobots fetches a robots.txt file and parses its rules; errors count as no rules
+func (c *Crawler) fetchRobots(robotsURL string) *RobotsRules {
+	ctx := context.Background()
+	if c.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.RequestTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return &RobotsRules{}
+	}
+	userAgent := c.userAgent()
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return &RobotsRules{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &RobotsRules{}
+	}
+
+	return ParseRobots(resp.Body, userAgent)
+}
@@ -0,0 +1,233 @@
+package crawler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /*.pdf$
+Disallow: /search?
+
+User-agent: docbot
+Disallow: /drafts/
+`
+
+func TestRobotsAllowed(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{"Mozilla/5.0", "/docs/intro.html", true},
+		{"Mozilla/5.0", "/private/notes.html", false},
+		{"Mozilla/5.0", "/private/public.html", true}, // Longer Allow wins
+		{"Mozilla/5.0", "/files/manual.pdf", false},
+		{"Mozilla/5.0", "/files/manual.pdf?download=1", true}, // $ anchors the end
+		{"Mozilla/5.0", "/search?q=go", false},
+		{"Mozilla/5.0", "/search", true},
+		{"Mozilla/5.0", "/robots.txt", true},
+		{"docbot/1.0", "/drafts/next.html", false},
+		{"docbot/1.0", "/private/notes.html", true}, // Its own group replaces the * group
+	}
+	for _, tt := range tests {
+		rules := ParseRobots(strings.NewReader(testRobots), tt.userAgent)
+		if got := rules.Allowed(tt.path); got != tt.want {
+			t.Errorf("Allowed(%q) for %q = %v, want %v", tt.path, tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestFetchRobotsDisallowed(t *testing.T) {
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = append(fetched, r.URL.Path)
+		if r.URL.Path == "/robots.txt" {
+			io.WriteString(w, "User-agent: *\nDisallow: /private/\n")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body><p>Page</p></body></html>")
+	}))
+	defer server.Close()
+
+	c := NewCrawler()
+	c.RespectRobotsTxt = true
+
+	if _, err := c.Fetch(server.URL + "/docs/"); err != nil {
+		t.Fatalf("Fetch(/docs/): %v", err)
+	}
+	_, err := c.Fetch(server.URL + "/private/page.html")
+	if !errors.Is(err, ErrRobotsDisallowed) {
+		t.Fatalf("Fetch(/private/page.html) error = %v, want ErrRobotsDisallowed", err)
+	}
+
+	// robots.txt is fetched once per host, and disallowed pages not at all
+	if want := "/robots.txt,/docs/"; strings.Join(fetched, ",") != want {
+		t.Errorf("requested %v, want %s", fetched, want)
+	}
+}
+
+func TestParseCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		robots    string
+		userAgent string
+		want      time.Duration
+		ok        bool
+	}{
+		{"generic", "User-agent: *\nCrawl-delay: 5\n", "Mozilla/5.0", 5 * time.Second, true},
+		{"fraction", "User-agent: *\nCrawl-delay: 0.5\n", "Mozilla/5.0", 500 * time.Millisecond, true},
+		{"none", "User-agent: *\nDisallow: /private/\n", "Mozilla/5.0", 0, false},
+		{"invalid", "User-agent: *\nCrawl-delay: soon\n", "Mozilla/5.0", 0, false},
+		{"case and comments", "user-agent: * # everyone\ncrawl-DELAY: 5 # seconds\n", "Mozilla/5.0", 5 * time.Second, true},
+		{
+			name:      "own group wins",
+			robots:    "User-agent: *\nCrawl-delay: 5\n\nUser-agent: docbot\nCrawl-delay: 1\n",
+			userAgent: "Mozilla/5.0 (compatible; DocBot/1.0)",
+			want:      time.Second,
+			ok:        true,
+		},
+		{
+			name:      "other group ignored",
+			robots:    "User-agent: otherbot\nCrawl-delay: 30\n\nUser-agent: *\nCrawl-delay: 5\n",
+			userAgent: "docbot/1.0",
+			want:      5 * time.Second,
+			ok:        true,
+		},
+		{
+			name:      "group of several agents",
+			robots:    "User-agent: otherbot\nUser-agent: docbot\nCrawl-delay: 2\n\nUser-agent: *\nCrawl-delay: 5\n",
+			userAgent: "docbot/1.0",
+			want:      2 * time.Second,
+			ok:        true,
+		},
+		{
+			name:      "own group without a delay",
+			robots:    "User-agent: *\nCrawl-delay: 5\n\nUser-agent: docbot\nDisallow: /drafts/\n",
+			userAgent: "docbot/1.0",
+			want:      5 * time.Second,
+			ok:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := ParseCrawlDelay(strings.NewReader(tt.robots), tt.userAgent)
+			if delay != tt.want || ok != tt.ok {
+				t.Errorf("ParseCrawlDelay() = %v, %v; want %v, %v", delay, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRespectCrawlDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			io.WriteString(w, "User-agent: *\nCrawl-delay: 0.2\n")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body><p>Page</p></body></html>")
+	}))
+	defer server.Close()
+
+	c := NewCrawler()
+	c.RespectCrawlDelay = true
+
+	start := time.Now()
+	for _, path := range []string{"/a", "/b", "/c"} {
+		if _, err := c.Fetch(server.URL + path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("three fetches took %v, want at least two crawl delays of 200ms", elapsed)
+	}
+}
+
+// pageServer serves robots.txt with the given content and a small page for every other path
+func pageServer(t *testing.T, robots string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			io.WriteString(w, robots)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body><p>Page</p></body></html>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCrawlDelayOtherHost(t *testing.T) {
+	slow := pageServer(t, "User-agent: *\nCrawl-delay: 1\n")
+	fast := pageServer(t, "")
+
+	c := NewCrawler()
+	c.RespectCrawlDelay = true
+	for _, server := range []*httptest.Server{slow, fast} {
+		if _, err := c.Fetch(server.URL + "/first"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second request to the slow host waits for its crawl delay
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Fetch(slow.URL + "/second")
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := c.Fetch(fast.URL + "/second"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("fetch from the other host took %v, want it not to wait for the 1s crawl delay", elapsed)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRobotsFetchOtherHost(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			<-release
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body><p>Page</p></body></html>")
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := pageServer(t, "User-agent: *\nDisallow: /private/\n")
+
+	c := NewCrawler()
+	c.RespectRobotsTxt = true
+	go c.Fetch(slow.URL + "/page")
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Fetch(fast.URL + "/private/page")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrRobotsDisallowed) {
+			t.Errorf("Fetch() error = %v, want ErrRobotsDisallowed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("fetch from the other host waited for a pending robots.txt")
+	}
+}
@@ -0,0 +1,73 @@
+package extractor
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Anchor is a link target in a page: the id of a heading and the heading's text
+type Anchor struct {
+	ID   string
+	Text string
+}
+
+// ExtractAnchors returns the anchors of the headings of a page in document order, for deep links such as "#setup".
+// A heading's anchor is its id attribute, or the name or id of an <a> inside it or right before it
+// (<a name="setup"></a><h2>Setup</h2>). When ids repeat, the first heading keeps the id.
+func (e *ContentExtractor) ExtractAnchors(doc *html.Node) []Anchor {
+	var anchors []Anchor
+	seen := make(map[string]bool)
+	var pending []string // Ids of <a> elements waiting for the next heading
+
+	add := func(id string, text string) {
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		anchors = append(anchors, Anchor{ID: id, Text: text})
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		// Text between an <a> and a heading means the anchor doesn't belong to the heading
+		if n.Type == html.TextNode && strings.TrimSpace(n.Data) != "" {
+			pending = nil
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				text := strings.Join(strings.Fields(textContent(n)), " ")
+				id, _ := getAttr(n, "id")
+				add(strings.TrimSpace(id), text)
+				for _, a := range e.findNodes(n, "a") {
+					add(anchorID(a), text)
+				}
+				for _, id := range pending {
+					add(id, text)
+				}
+				pending = nil
+				return
+			case "a":
+				if id := anchorID(n); id != "" && strings.TrimSpace(textContent(n)) == "" {
+					pending = append(pending, id)
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	return anchors
+}
+
+// anchorID returns the link target defined by an <a> element: its name, or else its id
+func anchorID(a *html.Node) string {
+	if name, ok := getAttr(a, "name"); ok && strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	id, _ := getAttr(a, "id")
+	return strings.TrimSpace(id)
+}
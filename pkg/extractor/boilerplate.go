@@ -0,0 +1,110 @@
+package extractor
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// boilerplateTags are the block elements RemoveBoilerplate compares across pages
+var boilerplateTags = map[string]bool{
+	"nav": true, "aside": true, "header": true, "footer": true, "section": true, "div": true,
+	"ul": true, "ol": true, "table": true, "form": true, "p": true,
+}
+
+// MinBoilerplatePages is the number of pages a block must appear on before RemoveBoilerplate removes it,
+// so small crawls don't lose the content their few pages share
+const MinBoilerplatePages = 3
+
+// RemoveBoilerplate strips blocks repeated across pages, like sidebars and footers that extraction left in the content.
+// A block is an element such as <nav>, <div> or <ul> with text; a block found with identical markup (ignoring whitespace)
+// on more than minShare of the pages, and on at least MinBoilerplatePages, is removed from all of them.
+// It returns the contents in the same order, and the number of blocks removed; contents without such blocks are unchanged.
+func (e *ContentExtractor) RemoveBoilerplate(contents []string, minShare float64) ([]string, int) {
+	pages := make([][]*html.Node, len(contents))
+	counts := make(map[string]int) // Number of pages each block appears on
+	for i, content := range contents {
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		roots, err := e.parseContent(content)
+		if err != nil {
+			continue
+		}
+		pages[i] = roots
+
+		seen := make(map[string]bool)
+		for _, root := range roots {
+			e.walkBlocks(root, func(n *html.Node, key string) bool {
+				if !seen[key] {
+					seen[key] = true
+					counts[key]++
+				}
+				return true
+			})
+		}
+	}
+
+	boilerplate := make(map[string]bool)
+	for key, n := range counts {
+		if n >= MinBoilerplatePages && float64(n) > minShare*float64(len(contents)) {
+			boilerplate[key] = true
+		}
+	}
+	if len(boilerplate) == 0 {
+		return contents, 0
+	}
+
+	result := make([]string, len(contents))
+	removed := 0
+	for i, roots := range pages {
+		var matches []*html.Node
+		for _, root := range roots {
+			e.walkBlocks(root, func(n *html.Node, key string) bool {
+				if boilerplate[key] {
+					matches = append(matches, n)
+					return false
+				}
+				return true
+			})
+		}
+		if len(matches) == 0 {
+			result[i] = contents[i]
+			continue
+		}
+
+		// Matching fragment roots have no parent; they are left out when rendering
+		dropped := make(map[*html.Node]bool)
+		for _, n := range matches {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			} else {
+				dropped[n] = true
+			}
+		}
+		var out strings.Builder
+		for _, root := range roots {
+			if !dropped[root] {
+				out.WriteString(e.renderNode(root))
+			}
+		}
+		result[i] = out.String()
+		removed += len(matches)
+	}
+
+	return result, removed
+}
+
+// walkBlocks calls visit with every block element with text below and including n, and the block's markup
+// with whitespace collapsed; the children of a block are skipped when visit returns false
+func (e *ContentExtractor) walkBlocks(n *html.Node, visit func(n *html.Node, key string) bool) {
+	if n.Type == html.ElementNode && boilerplateTags[n.Data] && strings.TrimSpace(textContent(n)) != "" {
+		key := strings.Join(strings.Fields(e.renderNode(n)), " ")
+		if !visit(n, key) {
+			return
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		e.walkBlocks(child, visit)
+	}
+}
@@ -0,0 +1,147 @@
+package extractor
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// dateLayouts are the formats ExtractDates accepts, from the most to the least precise
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// publishedMeta and updatedMeta are the <meta> names and properties holding the dates of a page
+var (
+	publishedMeta = []string{"article:published_time", "datePublished", "date"}
+	updatedMeta   = []string{"article:modified_time", "og:updated_time", "dateModified", "last-modified"}
+)
+
+// ExtractDates returns the publication and last update dates shown on a page.
+// Each date is taken from the first source that has it: <time datetime> elements, then <meta> tags
+// such as article:published_time, then the datePublished and dateModified of JSON-LD.
+// A <time> element counts as the update date when it or its parent is marked as such (dateModified, "updated", "modified");
+// otherwise the first one is the publication date. ok is false when no date was found.
+func (e *ContentExtractor) ExtractDates(doc *html.Node) (published, updated time.Time, ok bool) {
+	set := func(target *time.Time, value string) {
+		if target.IsZero() {
+			if t, parsed := parseDate(value); parsed {
+				*target = t
+			}
+		}
+	}
+
+	for _, timeNode := range e.findNodes(doc, "time") {
+		value, _ := getAttr(timeNode, "datetime")
+		if isUpdateTime(timeNode) {
+			set(&updated, value)
+		} else {
+			set(&published, value)
+		}
+	}
+
+	metadata := e.ExtractMetadata(doc)
+	for _, name := range publishedMeta {
+		set(&published, metadata[name])
+	}
+	for _, name := range updatedMeta {
+		set(&updated, metadata[name])
+	}
+	for _, meta := range e.findNodes(doc, "meta") {
+		// Microdata: <meta itemprop="datePublished" content="...">
+		itemprop, _ := getAttr(meta, "itemprop")
+		content, _ := getAttr(meta, "content")
+		switch itemprop {
+		case "datePublished":
+			set(&published, content)
+		case "dateModified":
+			set(&updated, content)
+		}
+	}
+
+	for _, script := range e.findNodes(doc, "script") {
+		if scriptType, _ := getAttr(script, "type"); !strings.EqualFold(strings.TrimSpace(scriptType), "application/ld+json") {
+			continue
+		}
+		var data any
+		if err := json.Unmarshal([]byte(textContent(script)), &data); err != nil {
+			continue
+		}
+		walkJSONLD(data, func(key, value string) {
+			switch key {
+			case "datePublished":
+				set(&published, value)
+			case "dateModified":
+				set(&updated, value)
+			}
+		})
+	}
+
+	return published, updated, !published.IsZero() || !updated.IsZero()
+}
+
+// isUpdateTime reports whether a <time> element, or its parent, is marked as the date of the last update
+func isUpdateTime(n *html.Node) bool {
+	for _, candidate := range []*html.Node{n, n.Parent} {
+		if candidate == nil || candidate.Type != html.ElementNode {
+			continue
+		}
+		for _, key := range []string{"itemprop", "class", "id"} {
+			value, _ := getAttr(candidate, key)
+			value = strings.ToLower(value)
+			if strings.Contains(value, "modified") || strings.Contains(value, "updated") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// walkJSONLD calls visit with every string property of a JSON-LD document, including nested objects, arrays and @graph.
+// The properties of an object are visited before those of the objects it contains, so a page's own dates
+// come before those of, say, its comments.
+func walkJSONLD(data any, visit func(key, value string)) {
+	switch v := data.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if s, ok := v[key].(string); ok {
+				visit(key, s)
+			}
+		}
+		for _, key := range keys {
+			walkJSONLD(v[key], visit)
+		}
+	case []any:
+		for _, item := range v {
+			walkJSONLD(item, visit)
+		}
+	}
+}
+
+// parseDate parses a date in one of dateLayouts
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,70 @@
+package extractor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractDates(t *testing.T) {
+	published := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		head      string
+		body      string
+		published time.Time
+		updated   time.Time
+		ok        bool
+	}{
+		{
+			name:      "time datetime",
+			body:      `<p>Posted <time datetime="2024-03-05">March 5</time>, <span class="updated"><time datetime="2024-06-01T09:30:00Z">June 1</time></span></p>`,
+			published: published,
+			updated:   updated,
+			ok:        true,
+		},
+		{
+			name:      "meta article:published_time",
+			head:      `<meta property="article:published_time" content="2024-03-05"><meta property="article:modified_time" content="2024-06-01T09:30:00Z">`,
+			published: published,
+			updated:   updated,
+			ok:        true,
+		},
+		{
+			name:      "microdata itemprop",
+			body:      `<div itemscope itemtype="https://schema.org/Article"><meta itemprop="datePublished" content="2024-03-05"><meta itemprop="dateModified" content="2024-06-01T09:30:00Z"></div>`,
+			published: published,
+			updated:   updated,
+			ok:        true,
+		},
+		{
+			name:      "JSON-LD",
+			head:      `<script type="application/ld+json">{"@context": "https://schema.org", "@graph": [{"@type": "Article", "datePublished": "2024-03-05", "dateModified": "2024-06-01T09:30:00Z"}]}</script>`,
+			published: published,
+			updated:   updated,
+			ok:        true,
+		},
+		{
+			name:      "time before meta",
+			head:      `<meta property="article:published_time" content="2020-01-01">`,
+			body:      `<time datetime="2024-03-05">March 5</time>`,
+			published: published,
+			ok:        true,
+		},
+		{
+			name: "invalid dates",
+			head: `<meta property="article:published_time" content="last week"><script type="application/ld+json">{"datePublished": </script>`,
+			body: `<time>yesterday</time>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTML(t, "<html><head>"+tt.head+"</head><body>"+tt.body+"</body></html>")
+			gotPublished, gotUpdated, ok := NewContentExtractor().ExtractDates(doc)
+			if !gotPublished.Equal(tt.published) || !gotUpdated.Equal(tt.updated) || ok != tt.ok {
+				t.Errorf("ExtractDates() = %v, %v, %v; want %v, %v, %v", gotPublished, gotUpdated, ok, tt.published, tt.updated, tt.ok)
+			}
+		})
+	}
+}
@@ -3,30 +3,92 @@ package extractor
 import (
 	"bytes"
 	"fmt"
+	"net/url"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/abadojack/whatlanggo"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
+// wordsPerMinute is the reading speed used to estimate reading time
+const wordsPerMinute = 200
+
+// DefaultRemoveTags lists the tags ExtractContent removes from the page body by default
+var DefaultRemoveTags = []string{"nav", "header", "footer", "aside", "script", "style", "iframe", "noscript"}
+
 // ContentExtractor is responsible for extracting useful content from web pages
 type ContentExtractor struct {
-	// Configuration items can be added here, such as specific selectors
+	ContentSelector string   // CSS selector of the content element, used by the selector strategy
+	StripSelectors  []string // CSS selectors of elements removed from extracted content (cookie banners, edit links, ...)
+	ExcludeText     []string // Patterns of text whose innermost block is removed from extracted content, see RemoveByText
+	RemoveTags      []string // Tags removed by ExtractContent; nil means DefaultRemoveTags
+	StripComments   bool     // Remove HTML comments (build artifacts, conditional comments) from extracted content
+
+	// Collapse runs of whitespace in extracted content and drop indentation between blocks; <pre> is left alone
+	NormalizeWhitespace bool
+
+	// Extraction strategies tried in order by ExtractMainContent (nil means DefaultStrategies);
+	// the first one yielding at least MinStrategyChars characters of text is used
+	Strategies       []string
+	MinStrategyChars int
 }
 
 // NewContentExtractor creates a new ContentExtractor instance
 func NewContentExtractor() *ContentExtractor {
-	return &ContentExtractor{}
+	return &ContentExtractor{
+		RemoveTags:    append([]string(nil), DefaultRemoveTags...),
+		StripComments: true,
+	}
+}
+
+// KeepTags removes the given tags from the removal list, so their content is kept
+func (e *ContentExtractor) KeepTags(tags []string) {
+	var remaining []string
+	for _, tag := range e.removeTags() {
+		keep := false
+		for _, kept := range tags {
+			if strings.EqualFold(tag, kept) {
+				keep = true
+				break
+			}
+		}
+		if !keep {
+			remaining = append(remaining, tag)
+		}
+	}
+	e.RemoveTags = append([]string{}, remaining...)
+}
+
+// removeTags returns the configured removal list, or the default one if none is set
+func (e *ContentExtractor) removeTags() []string {
+	if e.RemoveTags == nil {
+		return DefaultRemoveTags
+	}
+	return e.RemoveTags
 }
 
 // ExtractContent extracts the main content of a page
 func (e *ContentExtractor) ExtractContent(doc *html.Node) (string, error) {
+	if doc == nil {
+		return "", fmt.Errorf("no content found in HTML")
+	}
+
+	// html.Parse gives every document a body, even fragments; trees built otherwise,
+	// such as an element holding the nodes of html.ParseFragment, are extracted whole
 	body := e.findNode(doc, "body")
 	if body == nil {
-		return "", fmt.Errorf("no body tag found in HTML")
+		body = doc
 	}
 
 	// Remove unwanted tags (such as ads, navigation bars, etc.)
-	e.removeNodes(body, []string{"nav", "header", "footer", "aside", "script", "style", "iframe", "noscript"})
+	e.removeNodes(body, e.removeTags())
+	e.RemoveBySelector(body, e.StripSelectors)
+	e.RemoveByText(body, e.ExcludeText)
+	e.removeComments(body)
+	e.normalizeWhitespace(body)
 
 	// Get the cleaned content
 	content := e.renderNode(body)
@@ -34,29 +96,11 @@ func (e *ContentExtractor) ExtractContent(doc *html.Node) (string, error) {
 	return content, nil
 }
 
-// ExtractMainContent attempts to extract the main content part of the page, usually the article body
+// ExtractMainContent attempts to extract the main content part of the page, usually the article body.
+// The extraction strategies are tried in order; see ExtractWithStrategy.
 func (e *ContentExtractor) ExtractMainContent(doc *html.Node) (string, error) {
-	// Try to extract content from common content container tags
-	contentContainers := []string{
-		"article",
-		"main",
-		"div[class*='content']",
-		"div[id*='content']",
-		"div[class*='article']",
-		"div[id*='article']",
-	}
-
-	for _, selector := range contentContainers {
-		node := e.findNodeBySelector(doc, selector)
-		if node != nil {
-			// Remove interfering elements
-			e.removeNodes(node, []string{"script", "style", "iframe", "noscript", "nav"})
-			return e.renderNode(node), nil
-		}
-	}
-
-	// If no specific content container is found, fall back to extracting body content
-	return e.ExtractContent(doc)
+	content, _, err := e.ExtractWithStrategy(doc)
+	return content, err
 }
 
 // ExtractMetadata extracts metadata (title, author, etc.)
@@ -91,60 +135,379 @@ func (e *ContentExtractor) ExtractMetadata(doc *html.Node) map[string]string {
 	return metadata
 }
 
-// ConvertToMarkdown converts HTML to Markdown format
-func (e *ContentExtractor) ConvertToMarkdown(htmlContent string) string {
-	// Simple conversion, actual implementation may require more complex logic or a dedicated HTML-to-Markdown library
-	md := htmlContent
-
-	// Replace common HTML tags with Markdown syntax
-	replacements := []struct {
-		from string
-		to   string
-	}{
-		{"<h1>", "# "},
-		{"</h1>", "\n\n"},
-		{"<h2>", "## "},
-		{"</h2>", "\n\n"},
-		{"<h3>", "### "},
-		{"</h3>", "\n\n"},
-		{"<h4>", "#### "},
-		{"</h4>", "\n\n"},
-		{"<h5>", "##### "},
-		{"</h5>", "\n\n"},
-		{"<h6>", "###### "},
-		{"</h6>", "\n\n"},
-		{"<p>", ""},
-		{"</p>", "\n\n"},
-		{"<strong>", "**"},
-		{"</strong>", "**"},
-		{"<b>", "**"},
-		{"</b>", "**"},
-		{"<em>", "_"},
-		{"</em>", "_"},
-		{"<i>", "_"},
-		{"</i>", "_"},
-		{"<code>", "`"},
-		{"</code>", "`"},
-		{"<pre>", "```\n"},
-		{"</pre>", "\n```\n"},
-		{"<blockquote>", "> "},
-		{"</blockquote>", "\n\n"},
-		{"<ul>", "\n"},
-		{"</ul>", "\n"},
-		{"<ol>", "\n"},
-		{"</ol>", "\n"},
-		{"<li>", "- "},
-		{"</li>", "\n"},
-	}
-
-	for _, r := range replacements {
-		md = strings.ReplaceAll(md, r.from, r.to)
-	}
-
-	// Handle links
-	// TODO: Use regular expressions to handle more complex cases
-
-	return md
+// RemoveBySelector removes all descendants of n that match any of the given CSS selectors.
+// Invalid selectors are ignored.
+func (e *ContentExtractor) RemoveBySelector(n *html.Node, selectors []string) {
+	for _, selector := range selectors {
+		sel, err := ParseSelector(selector)
+		if err != nil {
+			continue
+		}
+
+		for _, match := range e.findNodesBySelector(n, sel, false) {
+			if match != n && match.Parent != nil {
+				match.Parent.RemoveChild(match)
+			}
+		}
+	}
+}
+
+// ExtractCanonicalURL returns the absolute URL declared by <link rel="canonical">, or an empty string
+func (e *ContentExtractor) ExtractCanonicalURL(doc *html.Node, base *url.URL) string {
+	for _, link := range e.findNodes(doc, "link") {
+		rel, _ := getAttr(link, "rel")
+		if containsWord(strings.ToLower(rel), "canonical") {
+			return linkTarget(link, base)
+		}
+	}
+
+	return ""
+}
+
+// ExtractNextPageURL returns the absolute URL of the next page of a paginated document, or an empty string.
+// It looks for <link rel="next"> or <a rel="next"> first, then for an anchor with the "next" class.
+func (e *ContentExtractor) ExtractNextPageURL(doc *html.Node, base *url.URL) string {
+	anchors := e.findNodes(doc, "a")
+	for _, n := range append(e.findNodes(doc, "link"), anchors...) {
+		rel, _ := getAttr(n, "rel")
+		if containsWord(strings.ToLower(rel), "next") {
+			if target := linkTarget(n, base); target != "" {
+				return target
+			}
+		}
+	}
+
+	for _, a := range anchors {
+		class, _ := getAttr(a, "class")
+		if containsWord(class, "next") {
+			if target := linkTarget(a, base); target != "" {
+				return target
+			}
+		}
+	}
+
+	return ""
+}
+
+// AbsolutizeURLs rewrites relative href, src and srcset attributes below n to absolute URLs,
+// resolved against the page URL (protocol-relative URLs take the page's scheme)
+func (e *ContentExtractor) AbsolutizeURLs(n *html.Node, base *url.URL) {
+	if n == nil || base == nil {
+		return
+	}
+
+	if n.Type == html.ElementNode {
+		for i, attr := range n.Attr {
+			switch attr.Key {
+			case "href", "src":
+				n.Attr[i].Val = resolveURL(base, attr.Val)
+			case "srcset":
+				n.Attr[i].Val = resolveSrcset(base, attr.Val)
+			}
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		e.AbsolutizeURLs(child, base)
+	}
+}
+
+// RewriteImages calls rewrite for the src of every <img> in an HTML fragment and replaces it with the result.
+// When a src is changed, the srcset attribute is dropped so the new source is used.
+func (e *ContentExtractor) RewriteImages(htmlContent string, rewrite func(src string) string) string {
+	roots, err := e.parseContent(htmlContent)
+	if err != nil {
+		return htmlContent
+	}
+
+	var out strings.Builder
+	for _, root := range roots {
+		for _, img := range e.findNodes(root, "img") {
+			e.rewriteImage(img, rewrite)
+		}
+		out.WriteString(e.renderNode(root))
+	}
+
+	return out.String()
+}
+
+// RewriteLinks calls rewrite for the href of every <a> in an HTML fragment and replaces it with the result
+func (e *ContentExtractor) RewriteLinks(htmlContent string, rewrite func(href string) string) string {
+	roots, err := e.parseContent(htmlContent)
+	if err != nil {
+		return htmlContent
+	}
+
+	var out strings.Builder
+	for _, root := range roots {
+		for _, a := range e.findNodes(root, "a") {
+			for i, attr := range a.Attr {
+				if attr.Key == "href" {
+					a.Attr[i].Val = rewrite(attr.Val)
+					break
+				}
+			}
+		}
+		out.WriteString(e.renderNode(root))
+	}
+
+	return out.String()
+}
+
+// TruncationMarker is appended to content cut by TruncateHTML
+const TruncationMarker = " […]"
+
+// TruncateHTML shortens extracted content to at most maxChars characters of text, cutting on a word boundary
+// and appending TruncationMarker. Markup stays well-formed; it reports whether the content was cut.
+func (e *ContentExtractor) TruncateHTML(htmlContent string, maxChars int) (string, bool) {
+	if maxChars <= 0 {
+		return htmlContent, false
+	}
+
+	roots, err := e.parseContent(htmlContent)
+	if err != nil {
+		return htmlContent, false
+	}
+
+	remaining := maxChars
+	truncated := false
+	var out strings.Builder
+	for _, root := range roots {
+		if truncated {
+			break
+		}
+		truncated = truncateNode(root, &remaining)
+		out.WriteString(e.renderNode(root))
+	}
+
+	if !truncated {
+		return htmlContent, false
+	}
+	return out.String(), true
+}
+
+// truncateNode cuts the text below n once remaining characters are used up, removing everything after the cut
+func truncateNode(n *html.Node, remaining *int) bool {
+	if n.Type == html.TextNode {
+		length := utf8.RuneCountInString(n.Data)
+		if length <= *remaining {
+			*remaining -= length
+			return false
+		}
+		n.Data = cutAtWord(n.Data, *remaining) + TruncationMarker
+		return true
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if truncateNode(child, remaining) {
+			for child.NextSibling != nil {
+				n.RemoveChild(child.NextSibling)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// cutAtWord returns at most maxChars characters of text, without a partial word at the end
+func cutAtWord(text string, maxChars int) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+
+	cut := maxChars
+	if !unicode.IsSpace(runes[cut]) {
+		for cut > 0 && !unicode.IsSpace(runes[cut-1]) {
+			cut--
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+}
+
+// rewriteImage applies rewrite to the src attribute of a single image
+func (e *ContentExtractor) rewriteImage(img *html.Node, rewrite func(src string) string) {
+	changed := false
+	for i, attr := range img.Attr {
+		if attr.Key == "src" {
+			newSrc := rewrite(attr.Val)
+			changed = newSrc != attr.Val
+			img.Attr[i].Val = newSrc
+			break
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	attrs := img.Attr[:0]
+	for _, attr := range img.Attr {
+		if attr.Key != "srcset" {
+			attrs = append(attrs, attr)
+		}
+	}
+	img.Attr = attrs
+}
+
+// PlainText returns the visible text of an HTML fragment, with text nodes separated by spaces
+func (e *ContentExtractor) PlainText(htmlContent string) string {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return ""
+	}
+
+	var parts []string
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+			return
+		}
+		if n.Type == html.TextNode {
+			if text := strings.TrimSpace(n.Data); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+
+	collect(doc)
+	return strings.Join(parts, " ")
+}
+
+// Stats computes the word count and the estimated reading time (in minutes, at 200 words per minute) of a text
+func (e *ContentExtractor) Stats(text string) (words int, readMinutes int) {
+	words = len(strings.Fields(text))
+	readMinutes = (words + wordsPerMinute - 1) / wordsPerMinute
+	return words, readMinutes
+}
+
+// minExcerptParagraphChars is the length below which a paragraph is left out of excerpts, like a "Note:" label
+const minExcerptParagraphChars = 40
+
+// Excerpt returns the text of the first meaningful paragraphs of a page, at most maxChars characters long.
+// Longer text is cut after its last complete sentence, or on a word boundary with an ellipsis
+// if that would drop more than half of it. Paragraphs in navigation, headers, footers and asides are skipped.
+func (e *ContentExtractor) Excerpt(doc *html.Node, maxChars int) string {
+	if doc == nil || maxChars <= 0 {
+		return ""
+	}
+
+	var paragraphs []string
+	length := 0
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "nav", "header", "footer", "aside", "script", "style", "noscript", "template":
+				return
+			case "p":
+				text := strings.Join(strings.Fields(textContent(n)), " ")
+				if chars := utf8.RuneCountInString(text); chars >= minExcerptParagraphChars {
+					paragraphs = append(paragraphs, text)
+					length += chars + 1
+				}
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil && length <= maxChars; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(doc)
+
+	excerpt := strings.Join(paragraphs, " ")
+	if utf8.RuneCountInString(excerpt) <= maxChars {
+		return excerpt
+	}
+
+	cut := cutAtWord(excerpt, maxChars)
+	if end := lastSentenceEnd(cut); end > len(cut)/2 {
+		return cut[:end]
+	}
+	return cutAtWord(excerpt, maxChars-1) + "…"
+}
+
+// lastSentenceEnd returns the byte offset after the last '.', '!' or '?' of text that ends a sentence, or 0 if there is none
+func lastSentenceEnd(text string) int {
+	for i := len(text) - 1; i >= 0; i-- {
+		switch text[i] {
+		case '.', '!', '?':
+			if i == len(text)-1 || text[i+1] == ' ' {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// DetectLanguage determines the language of a page as an ISO 639-1 code.
+// The <html lang> attribute is preferred; otherwise the language is detected from the given text.
+func (e *ContentExtractor) DetectLanguage(doc *html.Node, text string) string {
+	if htmlNode := e.findNode(doc, "html"); htmlNode != nil {
+		for _, attr := range htmlNode.Attr {
+			if attr.Key == "lang" && strings.TrimSpace(attr.Val) != "" {
+				return NormalizeLanguage(attr.Val)
+			}
+		}
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+
+	return whatlanggo.DetectLang(text).Iso6391()
+}
+
+// minRenderedTextChars is the amount of body text below which a page loading scripts is assumed to be rendered by JavaScript
+const minRenderedTextChars = 200
+
+// NeedsJavaScript reports whether a page looks like it is rendered on the client, such as a single-page application:
+// its body has very little text, but it loads external scripts.
+// It must be called on the document as fetched, before content is extracted from it.
+func (e *ContentExtractor) NeedsJavaScript(doc *html.Node) bool {
+	body := e.findNode(doc, "body")
+	if body == nil {
+		return false
+	}
+
+	scripts := 0
+	for _, script := range e.findNodes(doc, "script") {
+		if src, ok := getAttr(script, "src"); ok && strings.TrimSpace(src) != "" {
+			scripts++
+		}
+	}
+	if scripts == 0 {
+		return false
+	}
+
+	chars := 0
+	var count func(*html.Node)
+	count = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			chars += len(strings.Join(strings.Fields(n.Data), " "))
+		case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style" || n.Data == "noscript" || n.Data == "template"):
+			return
+		}
+		for child := n.FirstChild; child != nil && chars < minRenderedTextChars; child = child.NextSibling {
+			count(child)
+		}
+	}
+	count(body)
+
+	return chars < minRenderedTextChars
+}
+
+// NormalizeLanguage reduces a language tag such as "en-US" to its lowercase primary subtag ("en")
+func NormalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if idx := strings.IndexAny(tag, "-_"); idx != -1 {
+		tag = tag[:idx]
+	}
+	return tag
 }
 
 // Helper methods
@@ -182,46 +545,43 @@ func (e *ContentExtractor) findNodes(n *html.Node, tagName string) []*html.Node
 	return nodes
 }
 
-// findNodeBySelector finds a node using a simple selector
-// Note: This implementation only supports simple tag and attribute selectors, not full CSS selectors
+// findNodeBySelector finds the first node (in document order) matching a CSS selector.
+// Invalid selectors match nothing.
 func (e *ContentExtractor) findNodeBySelector(n *html.Node, selector string) *html.Node {
-	parts := strings.Split(selector, "[")
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil
+	}
 
-	tagName := parts[0]
-	var attrCondition string
-	if len(parts) > 1 {
-		attrCondition = strings.TrimSuffix(parts[1], "]")
+	nodes := e.findNodesBySelector(n, sel, true)
+	if len(nodes) == 0 {
+		return nil
 	}
+	return nodes[0]
+}
 
-	if n.Type == html.ElementNode && n.Data == tagName {
-		if attrCondition == "" {
-			return n // Pure tag selector
-		}
-
-		// Handle attribute selectors, e.g., div[class*='content']
-		if strings.Contains(attrCondition, "*=") {
-			// Contains relationship
-			keyValue := strings.Split(attrCondition, "*=")
-			if len(keyValue) == 2 {
-				attrName := strings.TrimSpace(keyValue[0])
-				attrValue := strings.Trim(keyValue[1], "'\"")
-
-				for _, attr := range n.Attr {
-					if attr.Key == attrName && strings.Contains(attr.Val, attrValue) {
-						return n
-					}
-				}
+// findNodesBySelector finds nodes matching a compiled selector, optionally stopping at the first match
+func (e *ContentExtractor) findNodesBySelector(n *html.Node, sel *Selector, first bool) []*html.Node {
+	var nodes []*html.Node
+
+	var walk func(*html.Node) bool
+	walk = func(current *html.Node) bool {
+		if sel.Match(current) {
+			nodes = append(nodes, current)
+			if first {
+				return true
 			}
 		}
-	}
-
-	for child := n.FirstChild; child != nil; child = child.NextSibling {
-		if found := e.findNodeBySelector(child, selector); found != nil {
-			return found
+		for child := current.FirstChild; child != nil; child = child.NextSibling {
+			if walk(child) {
+				return true
+			}
 		}
+		return false
 	}
 
-	return nil
+	walk(n)
+	return nodes
 }
 
 // removeNodes removes nodes with specified tags
@@ -246,6 +606,146 @@ func (e *ContentExtractor) removeNodes(n *html.Node, tagNames []string) {
 	}
 }
 
+// removeComments removes the comment nodes below n if comments are stripped
+func (e *ContentExtractor) removeComments(n *html.Node) {
+	if !e.StripComments {
+		return
+	}
+
+	var next *html.Node
+	for child := n.FirstChild; child != nil; child = next {
+		next = child.NextSibling
+		if child.Type == html.CommentNode {
+			n.RemoveChild(child)
+			continue
+		}
+		e.removeComments(child)
+	}
+}
+
+// blockElements are the elements whose surrounding whitespace is insignificant
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "dd": true, "details": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"summary": true, "table": true, "tbody": true, "td": true, "tfoot": true, "th": true, "thead": true,
+	"tr": true, "ul": true,
+}
+
+// normalizeWhitespace collapses the whitespace of the text below n if whitespace is normalized
+func (e *ContentExtractor) normalizeWhitespace(n *html.Node) {
+	if e.NormalizeWhitespace {
+		collapseWhitespace(n)
+	}
+}
+
+// collapseWhitespace replaces runs of whitespace in the text nodes below n with single spaces.
+// Whitespace next to block elements, like indentation, is removed; <pre> and <textarea> keep their text as is.
+func collapseWhitespace(n *html.Node) {
+	isBlock := func(node *html.Node) bool {
+		return node != nil && node.Type == html.ElementNode && blockElements[node.Data]
+	}
+
+	var next *html.Node
+	for child := n.FirstChild; child != nil; child = next {
+		next = child.NextSibling
+		switch child.Type {
+		case html.ElementNode:
+			if child.Data != "pre" && child.Data != "textarea" {
+				collapseWhitespace(child)
+			}
+		case html.TextNode:
+			text := strings.Join(strings.FieldsFunc(child.Data, isHTMLSpace), " ")
+			if text != "" && isHTMLSpace(rune(child.Data[0])) {
+				text = " " + text
+			}
+			if text != "" && isHTMLSpace(rune(child.Data[len(child.Data)-1])) {
+				text += " "
+			}
+			if text == "" && len(child.Data) > 0 {
+				text = " "
+			}
+
+			if isBlock(child.PrevSibling) || (child.PrevSibling == nil && isBlock(n)) {
+				text = strings.TrimLeft(text, " ")
+			}
+			if isBlock(child.NextSibling) || (child.NextSibling == nil && isBlock(n)) {
+				text = strings.TrimRight(text, " ")
+			}
+
+			if text == "" {
+				n.RemoveChild(child)
+			} else {
+				child.Data = text
+			}
+		}
+	}
+}
+
+// isHTMLSpace reports whether r is whitespace in HTML; unlike unicode.IsSpace, non-breaking spaces are not
+func isHTMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// resolveURL resolves a reference against a base URL, leaving it unchanged if it cannot be parsed
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ref
+	}
+
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return base.ResolveReference(refURL).String()
+}
+
+// linkTarget returns the href of a <link> or <a> element resolved against base, without fragment
+func linkTarget(n *html.Node, base *url.URL) string {
+	href, _ := getAttr(n, "href")
+	if strings.TrimSpace(href) == "" {
+		return ""
+	}
+
+	target, err := url.Parse(resolveURL(base, href))
+	if err != nil {
+		return ""
+	}
+	target.Fragment = ""
+	return target.String()
+}
+
+// resolveSrcset resolves every candidate URL of a srcset attribute ("a.png 1x, b.png 2x")
+func resolveSrcset(base *url.URL, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = resolveURL(base, fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// parseContent parses extracted content: a rendered <body> element or an HTML fragment
+func (e *ContentExtractor) parseContent(htmlContent string) ([]*html.Node, error) {
+	if strings.HasPrefix(htmlContent, "<body") {
+		doc, err := html.Parse(strings.NewReader(htmlContent))
+		if err != nil {
+			return nil, err
+		}
+		return []*html.Node{e.findNode(doc, "body")}, nil
+	}
+
+	bodyContext := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	return html.ParseFragment(strings.NewReader(htmlContent), bodyContext)
+}
+
 // renderNode converts a node to an HTML string
 func (e *ContentExtractor) renderNode(n *html.Node) string {
 	var buf bytes.Buffer
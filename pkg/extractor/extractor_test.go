@@ -0,0 +1,157 @@
+package extractor
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// parseHTML parses a document for a test
+func parseHTML(t *testing.T, source string) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// assertContains fails the test unless content contains each of the wanted strings
+func assertContains(t *testing.T, content string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(content, want) {
+			t.Errorf("content lacks %q:\n%s", want, content)
+		}
+	}
+}
+
+// assertNotContains fails the test if content contains any of the unwanted strings
+func assertNotContains(t *testing.T, content string, unwanted ...string) {
+	t.Helper()
+	for _, s := range unwanted {
+		if strings.Contains(content, s) {
+			t.Errorf("content contains %q:\n%s", s, content)
+		}
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	page := `<html><body><article><h1>Install</h1><!-- build artifact -->
+<p>Run the installer.</p><!--[if IE]><p>Upgrade your browser.</p><![endif]--></article></body></html>`
+	comments := []string{"<!-- build artifact -->", "<!--[if IE]><p>Upgrade your browser.</p><![endif]-->"}
+
+	if !NewContentExtractor().StripComments {
+		t.Error("comments are kept by default")
+	}
+
+	for _, strip := range []bool{true, false} {
+		e := NewContentExtractor()
+		e.StripComments = strip
+
+		content, err := e.ExtractContent(parseHTML(t, page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.ContentSelector = "article"
+		mainContent, err := e.ExtractMainContent(parseHTML(t, page))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, content := range []string{content, mainContent} {
+			assertContains(t, content, "<h1>Install</h1>", "<p>Run the installer.</p>")
+			if strip {
+				assertNotContains(t, content, "<!--", "build artifact", "Upgrade your browser")
+			} else {
+				assertContains(t, content, comments...)
+			}
+		}
+	}
+}
+
+func TestExtractContentWithoutBody(t *testing.T) {
+	fragment := `<h2>Install</h2><p>Run <code>go install</code>.</p><script>track()</script>`
+
+	t.Run("parsed document", func(t *testing.T) {
+		content, err := NewContentExtractor().ExtractContent(parseHTML(t, fragment))
+		if err != nil {
+			t.Fatalf("ExtractContent: %v", err)
+		}
+		assertContains(t, content, "<h2>Install</h2>", "<code>go install</code>")
+		assertNotContains(t, content, "track()")
+	})
+
+	t.Run("parsed fragment", func(t *testing.T) {
+		container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+		nodes, err := html.ParseFragment(strings.NewReader(fragment), container)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range nodes {
+			container.AppendChild(n)
+		}
+
+		content, err := NewContentExtractor().ExtractContent(container)
+		if err != nil {
+			t.Fatalf("ExtractContent: %v", err)
+		}
+		assertContains(t, content, "<h2>Install</h2>", "<code>go install</code>")
+		assertNotContains(t, content, "track()")
+	})
+}
+
+func TestKeepTags(t *testing.T) {
+	page := `<html><body><header><h1>Article title</h1></header>
+<aside><p>Main content in an aside</p></aside>
+<nav><a href="/">Home</a></nav><p>Body text</p></body></html>`
+
+	content, err := NewContentExtractor().ExtractContent(parseHTML(t, page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, content, "Article title", "Main content in an aside")
+
+	e := NewContentExtractor()
+	e.KeepTags([]string{"aside", "header"})
+	content, err = e.ExtractContent(parseHTML(t, page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, content, "Article title", "Main content in an aside", "Body text")
+	assertNotContains(t, content, "Home")
+}
+
+func TestRemoveTags(t *testing.T) {
+	e := NewContentExtractor()
+	e.RemoveTags = []string{"table"}
+
+	content, err := e.ExtractContent(parseHTML(t, `<html><body><table><tr><td>Layout</td></tr></table>
+<aside><p>Kept aside</p></aside></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, content, "Kept aside")
+	assertNotContains(t, content, "Layout")
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	page := "<html><body>\n    <div>\n\n      <p>\n        Some   indented\n        text.\n      </p>\n" +
+		"<pre>func main() {\n    fmt.Println(\"  spaced  \")\n}</pre>\n" +
+		"<pre><code>  indented\n\n    code</code></pre>\n    </div>\n  </body></html>"
+
+	e := NewContentExtractor()
+	e.NormalizeWhitespace = true
+	content, err := e.ExtractContent(parseHTML(t, page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, content,
+		"<p>Some indented text.</p>",
+		"<pre>func main() {\n    fmt.Println(&#34;  spaced  &#34;)\n}</pre>",
+		"<pre><code>  indented\n\n    code</code></pre>")
+	assertNotContains(t, content, "\n\n      ", "Some   indented")
+}
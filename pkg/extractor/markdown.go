@@ -0,0 +1,320 @@
+package extractor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ConvertToMarkdown converts HTML to Markdown format by walking the parsed HTML tree
+func (e *ContentExtractor) ConvertToMarkdown(htmlContent string) string {
+	bodyContext := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(htmlContent), bodyContext)
+	if err != nil {
+		return ""
+	}
+
+	c := &markdownConverter{}
+	for _, n := range nodes {
+		c.convert(n)
+	}
+
+	return c.String()
+}
+
+// markdownConverter accumulates the Markdown rendering of an HTML tree
+type markdownConverter struct {
+	out []byte
+}
+
+// String returns the converted Markdown with surrounding whitespace and extra blank lines removed
+func (c *markdownConverter) String() string {
+	md := string(c.out)
+	for strings.Contains(md, "\n\n\n") {
+		md = strings.ReplaceAll(md, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(md)
+}
+
+// convert writes the Markdown for a node and its descendants
+func (c *markdownConverter) convert(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.writeText(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		c.convertChildren(n)
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "noscript", "template":
+		// Not content
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(n.Data[1:])
+		c.block()
+		c.write(strings.Repeat("#", level) + " ")
+		c.convertChildren(n)
+		// Keep the heading's anchor with the {#id} attribute extension, so links to url#id still work
+		if id, _ := getAttr(n, "id"); strings.TrimSpace(id) != "" {
+			c.trimTrailingSpace()
+			c.write(" {#" + strings.TrimSpace(id) + "}")
+		}
+		c.block()
+	case "p", "div", "section", "article", "main", "header", "footer", "aside", "nav", "table", "tr":
+		c.block()
+		c.convertChildren(n)
+		c.block()
+	case "strong", "b":
+		c.wrapInline(n, "**")
+	case "em", "i":
+		c.wrapInline(n, "_")
+	case "code":
+		c.write("`" + textContent(n) + "`")
+	case "pre":
+		c.block()
+		c.write("```\n" + strings.Trim(textContent(n), "\n") + "\n```")
+		c.block()
+	case "blockquote":
+		c.block()
+		c.writePrefixed(c.sub(n), "> ")
+		c.block()
+	case "ul", "ol":
+		c.convertList(n)
+	case "dl":
+		c.convertDefinitionList(n)
+	case "figure":
+		c.convertFigure(n)
+	case "a":
+		href, _ := getAttr(n, "href")
+		if href == "" {
+			c.convertChildren(n)
+			return
+		}
+		c.write("[")
+		c.convertChildren(n)
+		c.write("](" + href + ")")
+	case "br":
+		c.trimTrailingSpace()
+		c.write("  \n")
+	case "hr":
+		// Blank lines around the rule keep it from turning the previous line into a heading
+		c.block()
+		c.write("---")
+		c.block()
+	case "img":
+		src, _ := getAttr(n, "src")
+		alt, _ := getAttr(n, "alt")
+		c.write("![" + alt + "](" + src + ")")
+	default:
+		c.convertChildren(n)
+	}
+}
+
+// convertChildren converts all children of a node
+func (c *markdownConverter) convertChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.convert(child)
+	}
+}
+
+// convertList writes an ordered or unordered list.
+// Nested lists are indented below their parent item by the width of the item's marker.
+func (c *markdownConverter) convertList(n *html.Node) {
+	ordered := n.Data == "ol"
+	index := 1
+	if start, ok := getAttr(n, "start"); ok {
+		if value, err := strconv.Atoi(start); err == nil {
+			index = value
+		}
+	}
+
+	c.block()
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.Data != "li" {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		// Keep list items tight: blank lines inside an item are dropped
+		item := strings.ReplaceAll(c.sub(child), "\n\n", "\n")
+		for i, line := range strings.Split(item, "\n") {
+			switch {
+			case i == 0:
+				c.write(marker + line)
+			case line != "":
+				c.write(strings.Repeat(" ", len(marker)) + line)
+			}
+			c.write("\n")
+		}
+	}
+	c.block()
+}
+
+// convertDefinitionList writes a definition list, e.g. the parameters of an API reference.
+// Every term becomes a bold line with its definitions indented below it.
+func (c *markdownConverter) convertDefinitionList(n *html.Node) {
+	c.block()
+	inDefinition := false
+	var walk func(*html.Node)
+	walk = func(parent *html.Node) {
+		for child := parent.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "dt":
+				// A blank line separates a term from the definitions of the previous one
+				if inDefinition {
+					c.write("\n")
+					inDefinition = false
+				}
+				if term := strings.ReplaceAll(c.sub(child), "\n", " "); term != "" {
+					c.write("**" + term + "**\n")
+				}
+			case "dd":
+				definition := strings.ReplaceAll(c.sub(child), "\n\n", "\n")
+				for _, line := range strings.Split(definition, "\n") {
+					if line != "" {
+						c.write("  " + line + "\n")
+					}
+				}
+				inDefinition = true
+			case "div":
+				// HTML allows wrapping each term and its definitions in a <div>
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	c.block()
+}
+
+// convertFigure writes the content of a figure, usually an image, followed by its <figcaption> as an italic line.
+// The caption comes last even if the markup puts it first.
+func (c *markdownConverter) convertFigure(n *html.Node) {
+	c.block()
+	var caption string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "figcaption" {
+			caption = strings.ReplaceAll(c.sub(child), "\n", " ")
+			continue
+		}
+		c.convert(child)
+	}
+	if caption != "" {
+		c.block()
+		c.write("_" + caption + "_")
+	}
+	c.block()
+}
+
+// sub converts the children of a node with a fresh converter and returns the result
+func (c *markdownConverter) sub(n *html.Node) string {
+	inner := &markdownConverter{}
+	inner.convertChildren(n)
+	return inner.String()
+}
+
+// wrapInline converts the children of a node surrounded by a Markdown delimiter
+func (c *markdownConverter) wrapInline(n *html.Node, delimiter string) {
+	c.write(delimiter)
+	c.convertChildren(n)
+	c.write(delimiter)
+}
+
+// writeText writes text with runs of whitespace collapsed.
+// Entities are already decoded by the HTML parser, and non-breaking spaces collapse like other whitespace.
+func (c *markdownConverter) writeText(text string) {
+	collapsed := strings.Join(strings.Fields(text), " ")
+	if collapsed == "" {
+		if text != "" && !c.atLineStart() {
+			c.write(" ")
+		}
+		return
+	}
+
+	if startsWithSpace(text) && !c.atLineStart() {
+		collapsed = " " + collapsed
+	}
+	if endsWithSpace(text) {
+		collapsed += " "
+	}
+
+	c.write(collapsed)
+}
+
+// writePrefixed writes every line of text with the given prefix
+func (c *markdownConverter) writePrefixed(text string, prefix string) {
+	for _, line := range strings.Split(text, "\n") {
+		c.write(strings.TrimRight(prefix+line, " ") + "\n")
+	}
+}
+
+// write appends raw Markdown to the output
+func (c *markdownConverter) write(s string) {
+	c.out = append(c.out, s...)
+}
+
+// trimTrailingSpace removes spaces and tabs at the end of the output
+func (c *markdownConverter) trimTrailingSpace() {
+	end := len(c.out)
+	for end > 0 && (c.out[end-1] == ' ' || c.out[end-1] == '\t') {
+		end--
+	}
+	c.out = c.out[:end]
+}
+
+// block ends the current block, leaving a blank line before whatever follows
+func (c *markdownConverter) block() {
+	end := len(c.out)
+	for end > 0 && (c.out[end-1] == ' ' || c.out[end-1] == '\t' || c.out[end-1] == '\n') {
+		end--
+	}
+
+	c.out = c.out[:end]
+	if end > 0 {
+		c.write("\n\n")
+	}
+}
+
+// atLineStart reports whether the output is empty or ends with a newline or space
+func (c *markdownConverter) atLineStart() bool {
+	return len(c.out) == 0 || c.out[len(c.out)-1] == '\n' || c.out[len(c.out)-1] == ' '
+}
+
+// textContent returns the concatenated text of a node and its descendants.
+// Entities are already decoded by the HTML parser; non-breaking spaces become plain spaces.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(*html.Node)
+	collect = func(current *html.Node) {
+		if current.Type == html.TextNode {
+			sb.WriteString(strings.ReplaceAll(current.Data, "\u00a0", " "))
+		}
+		for child := current.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+	return sb.String()
+}
+
+// startsWithSpace reports whether text begins with whitespace
+func startsWithSpace(text string) bool {
+	return text != "" && strings.TrimLeft(text, " \t\r\n") != text
+}
+
+// endsWithSpace reports whether text ends with whitespace
+func endsWithSpace(text string) bool {
+	return text != "" && strings.TrimRight(text, " \t\r\n") != text
+}
@@ -0,0 +1,38 @@
+package extractor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConvertToMarkdownGolden converts each testdata/markdown/*.html file and compares the result with the .md file of the same name
+func TestConvertToMarkdownGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden files found")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".html")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(strings.TrimSuffix(input, ".html") + ".md")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := NewContentExtractor().ConvertToMarkdown(string(source))
+			if got != strings.TrimSpace(string(want)) {
+				t.Errorf("ConvertToMarkdown(%s) =\n%s\nwant:\n%s", input, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled CSS selector.
+// It supports a practical subset of CSS: type (div), universal (*), class (.name), id (#name)
+// and attribute selectors ([attr], [attr=v], [attr~=v], [attr^=v], [attr$=v], [attr*=v]),
+// combined with descendant (" ") and child (">") combinators and grouped with commas.
+type Selector struct {
+	alternatives [][]selectorStep // Comma-separated alternatives, each a chain of steps
+}
+
+// selectorStep is one compound selector in a chain, with the combinator linking it to the previous step
+type selectorStep struct {
+	combinator byte // ' ' for descendant, '>' for child
+	compound   compoundSelector
+}
+
+// compoundSelector is a sequence of simple selectors that must all match the same element
+type compoundSelector struct {
+	tag     string
+	ids     []string
+	classes []string
+	attrs   []attrSelector
+}
+
+// attrSelector is a single attribute condition
+type attrSelector struct {
+	name  string
+	op    string // "" (presence), "=", "~=", "^=", "$=" or "*="
+	value string
+}
+
+// ParseSelector compiles a CSS selector
+func ParseSelector(selector string) (*Selector, error) {
+	sel := &Selector{}
+
+	for _, part := range splitTopLevel(selector, ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty selector", selector)
+		}
+
+		steps, err := parseSteps(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+		sel.alternatives = append(sel.alternatives, steps)
+	}
+
+	return sel, nil
+}
+
+// Match reports whether an element matches the selector
+func (s *Selector) Match(n *html.Node) bool {
+	for _, steps := range s.alternatives {
+		last := len(steps) - 1
+		if steps[last].compound.match(n) && matchAncestors(steps, last, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAncestors checks the steps before index i against the ancestors of n
+func matchAncestors(steps []selectorStep, i int, n *html.Node) bool {
+	if i == 0 {
+		return true
+	}
+
+	if steps[i].combinator == '>' {
+		parent := n.Parent
+		return parent != nil && steps[i-1].compound.match(parent) && matchAncestors(steps, i-1, parent)
+	}
+
+	for ancestor := n.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if steps[i-1].compound.match(ancestor) && matchAncestors(steps, i-1, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether an element satisfies every simple selector of the compound
+func (c *compoundSelector) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	if c.tag != "" && n.Data != c.tag {
+		return false
+	}
+
+	for _, id := range c.ids {
+		if value, ok := getAttr(n, "id"); !ok || value != id {
+			return false
+		}
+	}
+
+	for _, class := range c.classes {
+		value, _ := getAttr(n, "class")
+		if !containsWord(value, class) {
+			return false
+		}
+	}
+
+	for _, attr := range c.attrs {
+		if !attr.match(n) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match reports whether an element satisfies the attribute condition
+func (a *attrSelector) match(n *html.Node) bool {
+	value, ok := getAttr(n, a.name)
+	if !ok {
+		return false
+	}
+
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == a.value
+	case "~=":
+		return containsWord(value, a.value)
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	}
+	return false
+}
+
+// parseSteps parses a chain of compound selectors joined by combinators
+func parseSteps(s string) ([]selectorStep, error) {
+	var steps []selectorStep
+	combinator := byte(' ')
+	pendingChild := false
+
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+			continue
+		case '>':
+			if len(steps) == 0 || pendingChild {
+				return nil, fmt.Errorf("unexpected '>'")
+			}
+			combinator = '>'
+			pendingChild = true
+			i++
+			continue
+		}
+
+		compound, n, err := parseCompound(s[i:])
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, selectorStep{combinator: combinator, compound: compound})
+		combinator = ' '
+		pendingChild = false
+		i += n
+	}
+
+	if len(steps) == 0 || pendingChild {
+		return nil, fmt.Errorf("incomplete selector")
+	}
+
+	return steps, nil
+}
+
+// parseCompound parses a compound selector and returns the number of bytes consumed
+func parseCompound(s string) (compoundSelector, int, error) {
+	var c compoundSelector
+	i := 0
+
+	if s[0] == '*' {
+		i = 1
+	} else if name := readIdent(s); name != "" {
+		c.tag = strings.ToLower(name)
+		i = len(name)
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case '.', '#':
+			name := readIdent(s[i+1:])
+			if name == "" {
+				return c, 0, fmt.Errorf("missing name after '%c'", s[i])
+			}
+			if s[i] == '.' {
+				c.classes = append(c.classes, name)
+			} else {
+				c.ids = append(c.ids, name)
+			}
+			i += 1 + len(name)
+		case '[':
+			end := indexTopLevel(s[i:], ']')
+			if end == -1 {
+				return c, 0, fmt.Errorf("unterminated attribute selector")
+			}
+			attr, err := parseAttr(s[i+1 : i+end])
+			if err != nil {
+				return c, 0, err
+			}
+			c.attrs = append(c.attrs, attr)
+			i += end + 1
+		case ' ', '\t', '\n', '\r', '>':
+			return c, i, nil
+		default:
+			return c, 0, fmt.Errorf("unexpected character %q", s[i])
+		}
+	}
+
+	if i == 0 {
+		return c, 0, fmt.Errorf("empty compound selector")
+	}
+
+	return c, i, nil
+}
+
+// parseAttr parses the body of an attribute selector, without the brackets
+func parseAttr(body string) (attrSelector, error) {
+	eq := strings.Index(body, "=")
+	if eq == -1 {
+		name := strings.TrimSpace(body)
+		if name == "" {
+			return attrSelector{}, fmt.Errorf("empty attribute selector")
+		}
+		return attrSelector{name: strings.ToLower(name)}, nil
+	}
+
+	nameEnd := eq
+	op := "="
+	if eq > 0 && strings.ContainsRune("~^$*", rune(body[eq-1])) {
+		nameEnd = eq - 1
+		op = body[eq-1 : eq+1]
+	}
+
+	name := strings.TrimSpace(body[:nameEnd])
+	if name == "" {
+		return attrSelector{}, fmt.Errorf("missing attribute name in [%s]", body)
+	}
+
+	value := strings.TrimSpace(body[eq+1:])
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+
+	return attrSelector{name: strings.ToLower(name), op: op, value: value}, nil
+}
+
+// readIdent reads a CSS identifier from the start of s
+func readIdent(s string) string {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c == '-' || c == '_' || c >= 0x80 ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			i++
+			continue
+		}
+		break
+	}
+	return s[:i]
+}
+
+// splitTopLevel splits s on sep, ignoring separators inside brackets or quotes
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for {
+		idx := indexTopLevel(s[start:], sep)
+		if idx == -1 {
+			return append(parts, s[start:])
+		}
+		parts = append(parts, s[start:start+idx])
+		start += idx + 1
+	}
+}
+
+// indexTopLevel returns the index of the first c in s that is not inside quotes or (unless c is ']') brackets
+func indexTopLevel(s string, c byte) int {
+	var quote byte
+	inBrackets := false
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == c && (c == ']' || !inBrackets):
+			return i
+		case s[i] == '[':
+			inBrackets = true
+		case s[i] == ']':
+			inBrackets = false
+		}
+	}
+	return -1
+}
+
+// getAttr returns the value of an attribute and whether it is present
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// containsWord reports whether a whitespace-separated list contains the given word
+func containsWord(list string, word string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == word {
+			return true
+		}
+	}
+	return false
+}
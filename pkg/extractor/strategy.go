@@ -0,0 +1,147 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Extraction strategies, tried in order by ExtractMainContent
+const (
+	StrategySelector    = "selector"    // The element matching ContentSelector
+	StrategyReadability = "readability" // The element holding the most paragraph text
+	StrategyMain        = "main"        // The first common content container, such as <article>, <main> or role="main"
+	StrategyBody        = "body"        // The body without navigation and the other removed tags, like ExtractContent
+)
+
+// KnownStrategies lists every extraction strategy
+var KnownStrategies = []string{StrategySelector, StrategyReadability, StrategyMain, StrategyBody}
+
+// DefaultStrategies is the extraction chain used when ContentExtractor.Strategies is nil
+var DefaultStrategies = []string{StrategySelector, StrategyMain, StrategyBody}
+
+// contentContainers are the selectors of common content containers, tried in order by the main strategy
+var contentContainers = []string{
+	"article",
+	"main",
+	"[role='main']",
+	"div[class*='content']",
+	"div[id*='content']",
+	"div[class*='article']",
+	"div[id*='article']",
+}
+
+// minReadableParagraphChars is the length below which a paragraph doesn't count towards readability scores
+const minReadableParagraphChars = 25
+
+// IsStrategy reports whether name is a known extraction strategy
+func IsStrategy(name string) bool {
+	for _, strategy := range KnownStrategies {
+		if name == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractWithStrategy extracts the main content like ExtractMainContent, also returning the strategy that produced it.
+// Strategies are tried in order until one yields at least MinStrategyChars characters of text (any text if 0);
+// if none does, the body is used.
+func (e *ContentExtractor) ExtractWithStrategy(doc *html.Node) (string, string, error) {
+	if doc == nil {
+		return "", "", fmt.Errorf("no content found in HTML")
+	}
+
+	for _, strategy := range e.strategies() {
+		content, ok := e.extractStrategy(doc, strategy)
+		if ok && e.hasEnoughText(content) {
+			return content, strategy, nil
+		}
+	}
+
+	content, err := e.ExtractContent(doc)
+	return content, StrategyBody, err
+}
+
+// strategies returns the configured extraction chain, or the default one if none is set
+func (e *ContentExtractor) strategies() []string {
+	if e.Strategies == nil {
+		return DefaultStrategies
+	}
+	return e.Strategies
+}
+
+// extractStrategy extracts content with a single strategy; it reports false if the strategy finds nothing
+func (e *ContentExtractor) extractStrategy(doc *html.Node, strategy string) (string, bool) {
+	var container *html.Node
+	switch strategy {
+	case StrategySelector:
+		if e.ContentSelector != "" {
+			container = e.findNodeBySelector(doc, e.ContentSelector)
+		}
+	case StrategyReadability:
+		container = e.findReadableNode(doc)
+	case StrategyMain:
+		for _, selector := range contentContainers {
+			if container = e.findNodeBySelector(doc, selector); container != nil {
+				break
+			}
+		}
+	case StrategyBody:
+		content, err := e.ExtractContent(doc)
+		return content, err == nil
+	}
+	if container == nil {
+		return "", false
+	}
+
+	// Remove interfering elements
+	e.removeNodes(container, []string{"script", "style", "iframe", "noscript", "nav"})
+	e.RemoveBySelector(container, e.StripSelectors)
+	e.RemoveByText(container, e.ExcludeText)
+	e.removeComments(container)
+	e.normalizeWhitespace(container)
+	return e.renderNode(container), true
+}
+
+// hasEnoughText reports whether extracted content has at least MinStrategyChars characters of text, and any text at all
+func (e *ContentExtractor) hasEnoughText(content string) bool {
+	chars := utf8.RuneCountInString(e.PlainText(content))
+	return chars > 0 && chars >= e.MinStrategyChars
+}
+
+// findReadableNode returns the element holding the most paragraph text, or nil if the page has no real paragraphs.
+// Each paragraph scores its length for its parent and half of it for its grandparent, so a container
+// of many paragraphs wins over a single long one; ties go to the element first seen.
+func (e *ContentExtractor) findReadableNode(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]int)
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score int) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, seen := scores[n]; !seen {
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	for _, p := range e.findNodes(doc, "p") {
+		chars := utf8.RuneCountInString(strings.Join(strings.Fields(textContent(p)), " "))
+		if chars < minReadableParagraphChars || p.Parent == nil {
+			continue
+		}
+		addScore(p.Parent, chars)
+		addScore(p.Parent.Parent, chars/2)
+	}
+
+	var best *html.Node
+	for _, candidate := range candidates {
+		if best == nil || scores[candidate] > scores[best] {
+			best = candidate
+		}
+	}
+	return best
+}
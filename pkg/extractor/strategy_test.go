@@ -0,0 +1,73 @@
+package extractor
+
+import (
+	"testing"
+)
+
+func TestExtractRoleMain(t *testing.T) {
+	page := `<html><body><div class="layout">
+<div role="navigation"><a href="/">Home</a> | <a href="/docs/">Docs</a></div>
+<div class="sidebar"><p>Related pages</p></div>
+<div role="main"><h1>Install</h1><p>Run <code>go install</code>.</p><script>track()</script></div>
+<div class="footer">Copyright</div></div></body></html>`
+
+	e := NewContentExtractor()
+	content, strategy, err := e.ExtractWithStrategy(parseHTML(t, page))
+	if err != nil {
+		t.Fatalf("ExtractWithStrategy: %v", err)
+	}
+	if strategy != StrategyMain {
+		t.Errorf("strategy = %q, want %q", strategy, StrategyMain)
+	}
+	assertContains(t, content, `<div role="main">`, "<h1>Install</h1>", "<code>go install</code>")
+	assertNotContains(t, content, "Home", "Related pages", "Copyright", "track()")
+
+	mainContent, err := e.ExtractMainContent(parseHTML(t, page))
+	if err != nil {
+		t.Fatalf("ExtractMainContent: %v", err)
+	}
+	if mainContent != content {
+		t.Errorf("ExtractMainContent() = %q, want the content of the main strategy %q", mainContent, content)
+	}
+}
+
+func TestExtractMainContainerOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   string
+		want   string
+		absent string
+	}{
+		{
+			name:   "article before role main",
+			page:   `<html><body><div role="main"><p>Listing</p><article><p>Article text</p></article></div></body></html>`,
+			want:   "<article><p>Article text</p></article>",
+			absent: "Listing",
+		},
+		{
+			name:   "role main before content class",
+			page:   `<html><body><div class="content"><p>Wrapper</p></div><div role="main"><p>Main text</p></div></body></html>`,
+			want:   `<div role="main"><p>Main text</p></div>`,
+			absent: "Wrapper",
+		},
+		{
+			name:   "other roles ignored",
+			page:   `<html><body><div role="complementary"><p>Aside</p></div><div id="content"><p>Content text</p></div></body></html>`,
+			want:   `<div id="content"><p>Content text</p></div>`,
+			absent: "Aside",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, strategy, err := NewContentExtractor().ExtractWithStrategy(parseHTML(t, tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strategy != StrategyMain {
+				t.Errorf("strategy = %q, want %q", strategy, StrategyMain)
+			}
+			assertContains(t, content, tt.want)
+			assertNotContains(t, content, tt.absent)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package extractor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CompileTextPattern compiles a pattern of ExcludeText: a case-insensitive substring,
+// or a regular expression when written between slashes, like /deprecated since v\d+/
+func CompileTextPattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid text pattern %q: %v", pattern, err)
+		}
+		return re, nil
+	}
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("empty text pattern")
+	}
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern)), nil
+}
+
+// RemoveByText removes the descendants of n whose text matches any of the given patterns (see CompileTextPattern).
+// Only the innermost block element holding a match is removed, e.g. the paragraph of a deprecation notice and not
+// the section around it; matches in text directly inside n are left alone. Invalid patterns are ignored.
+func (e *ContentExtractor) RemoveByText(n *html.Node, patterns []string) {
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		if re, err := CompileTextPattern(pattern); err == nil {
+			res = append(res, re)
+		}
+	}
+	if len(res) == 0 {
+		return
+	}
+
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		e.removeTextMatches(child, res)
+		child = next
+	}
+}
+
+// removeTextMatches removes the innermost block elements below and including n whose text matches a pattern.
+// It reports whether a match was found, so the ancestors of a removed block are kept.
+func (e *ContentExtractor) removeTextMatches(n *html.Node, res []*regexp.Regexp) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	found := false
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if e.removeTextMatches(child, res) {
+			found = true
+		}
+		child = next
+	}
+	if found || !blockElements[n.Data] {
+		return found
+	}
+
+	text := textContent(n)
+	for _, re := range res {
+		if re.MatchString(text) {
+			n.Parent.RemoveChild(n)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package harvester
+
+import (
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// crawlConcurrently is crawl with up to MaxConcurrency pages fetched at the same time.
+// Only fetching runs in parallel; fetched pages are harvested and their links discovered one at a time,
+// so the web tree, statistics and storage are never accessed concurrently.
+// Pages are harvested in the order they arrive, which is CrawlOrder except among the pages in flight.
+func (hc *HarvesterContext) crawlConcurrently(rootNode *node.WebNode) {
+	results := make(chan fetchResult)
+	inFlight := 0
+
+	for {
+		// Start fetches while there are free workers; the page limit counts the fetches in flight
+		for hc.WebTree.Frontier.Len() > 0 && inFlight < hc.MaxConcurrency && !hc.stopped() &&
+			(hc.MaxPages <= 0 || hc.Stats.PagesFetched+inFlight < hc.MaxPages) {
+			n := hc.dequeue()
+			if n == nil {
+				break
+			}
+			inFlight++
+			go func() {
+				result := hc.fetchResponse(n.URL.String())
+				result.node = n
+				results <- result
+			}()
+		}
+		if inFlight == 0 {
+			return
+		}
+
+		result := <-results
+		inFlight--
+		hc.countFetch(result)
+
+		// Pages in flight when the crawl stops are still harvested, but no further fetches are started
+		if result.err != nil {
+			hc.fetchFailed(rootNode, result.node, result.err)
+			continue
+		}
+		hc.enqueue(hc.downloadPage(rootNode, result.node, result.doc, result.header))
+	}
+}
@@ -0,0 +1,193 @@
+package harvester
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qrtt1/doc-harvester/pkg/crawler"
+	"github.com/qrtt1/doc-harvester/pkg/storage"
+)
+
+// slowly delays every response of a handler
+func slowly(h http.Handler, delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// inFlightMeter records the most requests a handler served at the same time
+type inFlightMeter struct {
+	mutex   sync.Mutex
+	current int
+	max     int
+}
+
+// Measure returns h, counting the requests it serves
+func (m *inFlightMeter) Measure(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mutex.Lock()
+		m.current++
+		m.max = max(m.max, m.current)
+		m.mutex.Unlock()
+
+		h.ServeHTTP(w, r)
+
+		m.mutex.Lock()
+		m.current--
+		m.mutex.Unlock()
+	})
+}
+
+// Max returns the most requests that were in flight at the same time
+func (m *inFlightMeter) Max() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.max
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestConcurrentHarvestOutputsOnce(t *testing.T) {
+	// Twenty pages linking to each other, to the start page and to the same page twice,
+	// so several workers discover the same links at the same time
+	const pages = 20
+	name := func(i int) string { return "p" + strconv.Itoa(i%pages) + ".html" }
+	site := &fixtureSite{Pages: map[string]string{}}
+	var start []string
+	for i := 0; i < pages; i++ {
+		start = append(start, name(i))
+		site.Pages["/docs/"+name(i)] = fixturePage("Page "+strconv.Itoa(i), name(i+1), name(i+7), name(i+7), "/docs/")
+	}
+	site.Pages["/docs/"] = fixturePage("Docs", start...)
+	meter := &inFlightMeter{}
+	server := httptest.NewServer(meter.Measure(slowly(site, 20*time.Millisecond)))
+	t.Cleanup(server.Close)
+
+	var logs lockedBuffer
+	hc, xmlPath := newXMLTestContext(t, server.URL+"/docs/", WithMaxDepth(5),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	hc.Scope = ScopeSubtree
+	hc.MaxConcurrency = 8
+	download(t, hc)
+
+	if got := meter.Max(); got < 2 {
+		t.Errorf("at most %d requests in flight, want the pages fetched concurrently", got)
+	}
+
+	// Each page is requested and written once
+	requests := make(map[string]int)
+	for _, path := range site.Requested() {
+		requests[path]++
+	}
+	data, err := os.ReadFile(xmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc storage.XMLDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid XML output: %v", err)
+	}
+	written := make(map[string]int)
+	for _, page := range doc.Pages {
+		written[strings.TrimPrefix(page.URL, server.URL)]++
+	}
+	for path := range site.Pages {
+		if requests[path] != 1 || written[path] != 1 {
+			t.Errorf("%s requested %d times and written %d times, want once each", path, requests[path], written[path])
+		}
+	}
+	if len(doc.Pages) != pages+1 {
+		t.Errorf("wrote %d pages, want %d", len(doc.Pages), pages+1)
+	}
+
+	// Each discovered URL is output once
+	found := make(map[string]int)
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if !strings.Contains(line, `msg="Found parent URL"`) {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if u, ok := strings.CutPrefix(field, "url="); ok {
+				found[strings.TrimPrefix(u, server.URL)]++
+			}
+		}
+	}
+	for i := 0; i < pages; i++ {
+		if path := "/docs/" + name(i); found[path] != 1 {
+			t.Errorf("%s output %d times, want once", path, found[path])
+		}
+	}
+}
+
+func TestConcurrencyPerHostTwoHosts(t *testing.T) {
+	const perHost, pages = 2, 8
+	total := &inFlightMeter{}
+
+	// The seed's host and an external host, each serving pages slowly
+	external := &fixtureSite{Pages: map[string]string{}}
+	externalMeter := &inFlightMeter{}
+	externalServer := httptest.NewServer(total.Measure(externalMeter.Measure(slowly(external, 30*time.Millisecond))))
+	t.Cleanup(externalServer.Close)
+
+	site := &fixtureSite{Pages: map[string]string{}}
+	siteMeter := &inFlightMeter{}
+	server := httptest.NewServer(total.Measure(siteMeter.Measure(slowly(site, 30*time.Millisecond))))
+	t.Cleanup(server.Close)
+
+	// The start page alternates between the hosts, so both have pages waiting at the same time
+	var links []string
+	for i := 0; i < pages; i++ {
+		name := "p" + strconv.Itoa(i) + ".html"
+		site.Pages["/docs/"+name] = fixturePage("Page " + strconv.Itoa(i))
+		external.Pages["/spec/"+name] = fixturePage("Spec " + strconv.Itoa(i))
+		links = append(links, name, externalServer.URL+"/spec/"+name)
+	}
+	site.Pages["/docs/"] = fixturePage("Docs", links...)
+
+	c := crawler.NewCrawler()
+	c.MaxConcurrencyPerHost = perHost
+	hc, xmlPath := newXMLTestContext(t, server.URL+"/docs/", WithCrawler(c))
+	hc.Scope = ScopeSubtree
+	hc.FollowExternalDepth = 1
+	hc.MaxConcurrency = 6
+	download(t, hc)
+
+	if got := len(site.Requested()) + len(external.Requested()); got != 2*pages+1 {
+		t.Errorf("made %d requests, want %d", got, 2*pages+1)
+	}
+	if got := len(readXMLPages(t, server, xmlPath)); got != 2*pages+1 {
+		t.Errorf("stored %d pages, want %d", got, 2*pages+1)
+	}
+	if siteMeter.Max() > perHost || externalMeter.Max() > perHost {
+		t.Errorf("at most %d and %d requests in flight per host, want no more than %d", siteMeter.Max(), externalMeter.Max(), perHost)
+	}
+	if total.Max() <= perHost {
+		t.Errorf("at most %d requests in flight in total, want more than the per-host limit of %d", total.Max(), perHost)
+	}
+}
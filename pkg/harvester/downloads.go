@@ -0,0 +1,123 @@
+package harvester
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// FileStorage is implemented by storages that can keep downloaded files next to the harvested content
+type FileStorage interface {
+	// SaveFile stores a downloaded file under a name derived from its URL and returns the reference to record
+	SaveFile(name string, data []byte) (string, error)
+}
+
+// DefaultDownloadExtensions are the file extensions of links cataloged as downloads instead of crawled as pages
+var DefaultDownloadExtensions = []string{
+	"pdf", "zip", "gz", "tgz", "bz2", "xz", "7z",
+	"csv", "tsv", "xls", "xlsx", "doc", "docx", "ppt", "pptx", "odt", "ods", "epub",
+}
+
+// downloadExtensions returns the configured download extensions, lowercased and without a leading dot
+func (hc *HarvesterContext) downloadExtensions() []string {
+	if hc.DownloadExtensions == nil {
+		return DefaultDownloadExtensions
+	}
+
+	extensions := make([]string, 0, len(hc.DownloadExtensions))
+	for _, ext := range hc.DownloadExtensions {
+		extensions = append(extensions, strings.ToLower(strings.TrimPrefix(ext, ".")))
+	}
+	return extensions
+}
+
+// isDownloadLink reports whether a link's path ends with one of the download extensions
+func (hc *HarvesterContext) isDownloadLink(link string) bool {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(linkURL.Path), "."))
+	if ext == "" {
+		return false
+	}
+	for _, downloadExt := range hc.downloadExtensions() {
+		if ext == downloadExt {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDownloadLink reports whether a link is a download, counting it as skipped; downloads are never crawled as pages
+func (hc *HarvesterContext) skipDownloadLink(link string) bool {
+	if !hc.isDownloadLink(link) {
+		return false
+	}
+	hc.Stats.skip("download")
+	hc.Logger.Debug("Filtered (download)", "url", link)
+	return true
+}
+
+// collectDownloads records the download links of a page, fetching the files if DownloadFiles is set
+func (hc *HarvesterContext) collectDownloads(n *node.WebNode) {
+	seen := make(map[string]bool)
+	for _, link := range n.Links {
+		fileURL := hc.removeFragment(link.URL)
+		if seen[fileURL] || !hc.isDownloadLink(fileURL) {
+			continue
+		}
+		seen[fileURL] = true
+
+		download := node.Download{URL: fileURL, Text: link.Text}
+		if hc.DownloadFiles {
+			download.Path = hc.downloadFile(fileURL)
+		}
+		n.Downloads = append(n.Downloads, download)
+	}
+}
+
+// downloadFile fetches a file and stores it, returning the stored reference or "" if that failed.
+// Files linked from several pages are fetched once.
+func (hc *HarvesterContext) downloadFile(fileURL string) string {
+	if ref, ok := hc.fileCache[fileURL]; ok {
+		return ref
+	}
+	if hc.fileCache == nil {
+		hc.fileCache = make(map[string]string)
+	}
+
+	ref, err := hc.fetchFile(fileURL)
+	if err != nil {
+		hc.Logger.Warn("Failed to download file", "url", fileURL, "error", err)
+	}
+	hc.fileCache[fileURL] = ref
+	return ref
+}
+
+// fetchFile fetches a file and saves it to the storage
+func (hc *HarvesterContext) fetchFile(fileURL string) (string, error) {
+	fileStorage, ok := hc.Storage.(FileStorage)
+	if !ok {
+		return "", fmt.Errorf("storage can't keep files")
+	}
+	assetFetcher, ok := hc.Crawler.(AssetFetcher)
+	if !ok {
+		return "", fmt.Errorf("page fetcher can't download files")
+	}
+
+	data, _, err := assetFetcher.FetchAsset(fileURL, hc.MaxAssetSize)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+	return fileStorage.SaveFile(path.Base(parsed.Path), data)
+}
@@ -0,0 +1,103 @@
+package harvester_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	"golang.org/x/net/html"
+
+	"github.com/qrtt1/doc-harvester/pkg/crawler"
+	"github.com/qrtt1/doc-harvester/pkg/harvester"
+	"github.com/qrtt1/doc-harvester/pkg/storage"
+)
+
+// wikiExtractor extracts the content of a wiki whose articles are in <div id="wiki-body">
+type wikiExtractor struct{}
+
+// ExtractContent renders the whole body
+func (wikiExtractor) ExtractContent(doc *html.Node) (string, error) {
+	return render(find(doc, func(n *html.Node) bool { return n.Data == "body" }))
+}
+
+// ExtractMainContent renders the wiki body only
+func (wikiExtractor) ExtractMainContent(doc *html.Node) (string, error) {
+	return render(find(doc, func(n *html.Node) bool {
+		for _, attr := range n.Attr {
+			if attr.Key == "id" && attr.Val == "wiki-body" {
+				return true
+			}
+		}
+		return false
+	}))
+}
+
+// ExtractMetadata returns the title of the page
+func (wikiExtractor) ExtractMetadata(doc *html.Node) map[string]string {
+	metadata := make(map[string]string)
+	if title := find(doc, func(n *html.Node) bool { return n.Data == "title" }); title != nil && title.FirstChild != nil {
+		metadata["title"] = title.FirstChild.Data
+	}
+	return metadata
+}
+
+// find returns the first element for which match is true
+func find(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// render renders an element, failing if there is none
+func render(n *html.Node) (string, error) {
+	if n == nil {
+		return "", fmt.Errorf("element not found")
+	}
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	return buf.String(), err
+}
+
+func ExampleWithExtractor() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>Setup</title></head><body>
+<div class="menu">Home | Setup</div><div id="wiki-body"><p>Install the tool.</p></div></body></html>`)
+	}))
+	defer server.Close()
+
+	pages := storage.NewMemoryStorage()
+	hc, err := harvester.NewHarvesterContext(server.URL+"/wiki/Setup",
+		harvester.WithCrawler(crawler.NewCrawler()),
+		harvester.WithExtractor(wikiExtractor{}),
+		harvester.WithStorage(pages),
+		harvester.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	hc.SinglePage = true
+	if err := hc.Download(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	hc.Cleanup()
+
+	for _, page := range pages.Pages() {
+		fmt.Println(page.Title)
+		fmt.Println(page.Content)
+	}
+	// Output:
+	// Setup
+	// <div id="wiki-body"><p>Install the tool.</p></div>
+}
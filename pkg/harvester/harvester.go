@@ -1,9 +1,19 @@
 package harvester
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"net/url"
+	"path"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 
@@ -22,6 +32,50 @@ type Storage interface {
 	CreateIndexFile(path string) error
 }
 
+// BoilerplateRemover is implemented by storages that can strip blocks repeated across the stored pages
+type BoilerplateRemover interface {
+	// RemoveBoilerplate strips blocks found on more than minShare of the pages and returns how many were removed
+	RemoveBoilerplate(minShare float64) int
+}
+
+// PageFetcher defines how pages are fetched and parsed; *crawler.Crawler is the default implementation
+type PageFetcher interface {
+	// FetchPage fetches and parses a page
+	FetchPage(urlStr string) (*html.Node, error)
+	// ExtractLinks extracts all links from a page, resolved against the base URL
+	ExtractLinks(doc *html.Node, baseURLStr string) ([]string, error)
+	// ExtractTitle extracts the page title
+	ExtractTitle(doc *html.Node) string
+}
+
+// ResponseFetcher is implemented by page fetchers that also return response metadata such as headers
+type ResponseFetcher interface {
+	// Fetch fetches and parses a page, returning it with its response headers
+	Fetch(urlStr string) (*crawler.Page, error)
+}
+
+// LinkDetailer is implemented by page fetchers that extract links with their rel attribute and anchor text
+type LinkDetailer interface {
+	// ExtractLinksDetailed extracts all links from a page, resolved against the base URL
+	ExtractLinksDetailed(doc *html.Node, baseURLStr string) ([]crawler.Link, error)
+}
+
+// Extractor extracts the content and metadata of a page; *extractor.ContentExtractor is the default implementation
+type Extractor interface {
+	// ExtractContent extracts the cleaned content of the whole page
+	ExtractContent(doc *html.Node) (string, error)
+	// ExtractMainContent extracts the cleaned main content of the page, leaving out surrounding navigation
+	ExtractMainContent(doc *html.Node) (string, error)
+	// ExtractMetadata returns the <meta> names and properties of the page with their content
+	ExtractMetadata(doc *html.Node) map[string]string
+}
+
+// AssetFetcher is implemented by page fetchers that can also download binary assets
+type AssetFetcher interface {
+	// FetchAsset downloads an asset of at most maxSize bytes, returning its data and content type
+	FetchAsset(urlStr string, maxSize int64) ([]byte, string, error)
+}
+
 // NullStorage is used for exploration mode, doesn't actually store content
 type NullStorage struct{}
 
@@ -37,133 +91,221 @@ func (s *NullStorage) CreateIndexFile(path string) error {
 	return nil
 }
 
+// Crawl orders
+const (
+	CrawlOrderBFS = "bfs" // Breadth-first: all pages of a depth before the pages of the next depth
+	CrawlOrderDFS = "dfs" // Depth-first: the pages linked from a page before the page's siblings
+)
+
+// Link scopes
+const (
+	ScopeParent  = "parent"  // Links to the parent directory of the seed and to any prompt-engineering page of its host
+	ScopeSubtree = "subtree" // Links to the seed's directory and the paths below it, nothing above
+)
+
 // HarvesterContext encapsulates all components and operations related to website exploration and downloading
 type HarvesterContext struct {
-	Crawler     *crawler.Crawler
+	Crawler     PageFetcher
 	WebTree     *tree.WebTree
-	Extractor   *extractor.ContentExtractor
+	Extractor   Extractor
 	Storage     Storage
 	RootURL     string
 	BaseURL     string
-	MaxDepth    int
+	MaxDepth    int // Maximum crawling depth; 0 or less means unlimited
+	MaxPages    int // Stop the crawl after fetching this many pages (0 means unlimited)
 	Debug       bool
-	DownloadAll bool            // Whether to download all pages
-	PrintedURLs map[string]bool // Used to track URLs that have been output
-}
+	DownloadAll bool      // Whether to download all pages
+	SinglePage  bool      // Whether to download only the seed pages, without discovering links
+	OnlyLang    string    // If set, only pages in this language (ISO 639-1) are saved
+	Since       time.Time // If set, pages whose Last-Modified header is older are not saved; their links are still followed
+	PrintedURLs *URLSet   // URLs that have been output, shared by the goroutines of a concurrent crawl
 
-// NewExplorerContext creates a new exploration context (without downloading content)
-func NewExplorerContext(rootURL string, maxDepth int, debug bool) (*HarvesterContext, error) {
-	// Create crawler
-	c := crawler.NewCrawler()
+	MaxContentChars    int                         // Truncate stored content to this many characters of text (0 means unlimited)
+	ContentTransformer func(content string) string // Rewrites extracted content before it is stored, e.g. a summarizer
+	PruneEmpty         bool                        // Whether to skip storing pages with less than MinContentChars characters of text
+	ExcerptChars       int                         // Length of the excerpt stored with each page (0 means none)
+	DumpRaw            bool                        // Whether to also store the fetched HTML of each page, before extraction
+	MinContentChars    int                         // Text length below which PruneEmpty skips a page
 
-	// Create web tree
-	webTree, err := tree.NewWebTree(rootURL, maxDepth)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create web tree: %w", err)
-	}
+	// If above 0, Cleanup strips blocks repeated on more than this share of the pages, such as sidebars and footers,
+	// before the output is written; the storage must implement BoilerplateRemover
+	BoilerplateShare float64
+
+	TokenEstimator storage.TokenEstimator // Estimates the tokens of a page's text; storage.EstimateTokens when nil
+	MaxTokens      int                    // Stop the crawl once the saved pages reach this many estimated tokens (0 means unlimited)
+	totalTokens    int                    // Estimated tokens of all saved pages
+
+	MaxOutputSize int64 // Stop the crawl before the content of the saved pages exceeds this many bytes (0 means unlimited)
+	outputSize    int64 // Bytes of content of all saved pages
+
+	FollowPagination  bool // Whether to follow rel="next" chains regardless of the parent-path filter
+	RespectRobotsMeta bool // Whether to honor noindex/nofollow from <meta name="robots"> and X-Robots-Tag
+	RespectNofollow   bool // Whether to skip links marked rel="nofollow"
+	MaxPathRepetition int  // Refuse links whose path repeats a segment more often than this, e.g. /a/b/a/b/a/b (0 means no limit)
+	MaxLinksPerPage   int  // Only follow the first this many accepted links of each page (0 means no limit)
+
+	// Order pages are fetched in: CrawlOrderBFS (also when empty) or CrawlOrderDFS.
+	// Both apply the same depth limit and mark links visited when they are found; depth-first,
+	// a page first found through a longer path than its shortest one keeps the longer path's depth.
+	CrawlOrder string
+
+	// Pages of the seed's host that are followed: ScopeParent (also when empty), kept for compatibility, or ScopeSubtree
+	Scope string
+
+	// Hops to pages on other hosts, e.g. 1 to also store the pages the crawled site links to directly (0 means none).
+	// Off-host pages are never crawled like the site itself; their links are only followed to further hops within the budget.
+	FollowExternalDepth int
+
+	DownloadAssets      bool              // Whether to download images and embed them in the stored content
+	MaxAssetSize        int64             // Maximum size of a downloaded asset in bytes (0 means unlimited)
+	AllowExternalAssets bool              // Whether to download images hosted on other hosts
+	AssetFiles          bool              // Whether to store assets as files of a FileStorage instead of embedding them as data URIs
+	assetCache          map[string]string // Asset URL -> local reference, so shared images are fetched once
 
-	// Create content extractor
-	e := extractor.NewContentExtractor()
+	DownloadExtensions []string          // Extensions of links cataloged as downloads; DefaultDownloadExtensions when nil
+	DownloadFiles      bool              // Whether to fetch the cataloged downloads into a FileStorage
+	fileCache          map[string]string // Download URL -> stored reference, so shared files are fetched once
 
-	// Create null storage (does not actually save files)
-	s := &NullStorage{}
+	// Hooks for library consumers; nil hooks are skipped
+	OnPageFetched    func(node *node.WebNode, content string) // Called after a page's content has been extracted
+	OnLinkDiscovered func(link string) (follow bool)          // Called for each discovered link; return false to skip it
+	OnError          func(url string, err error)              // Called when processing a page fails; errors are logged when nil
 
-	return &HarvesterContext{
-		Crawler:     c,
-		WebTree:     webTree,
-		Extractor:   e,
-		Storage:     s,
-		RootURL:     rootURL,
-		BaseURL:     rootURL,
-		MaxDepth:    maxDepth,
-		Debug:       debug,
-		PrintedURLs: make(map[string]bool), // Initialize printed URLs map
-	}, nil
+	Logger *slog.Logger // Logger for progress and diagnostic messages
+
+	Stats         CrawlStats    // Counters of the crawl, reported at the end of a run
+	SlowThreshold time.Duration // Pages taking longer to fetch and extract are logged (0 means no logging)
+
+	// Pages fetched in parallel by the crawl; a *crawler.Crawler also limits the requests in flight per host
+	MaxConcurrency int
+
+	RetryFailed  bool          // Whether to retry the pages that failed once the crawl is done
+	RetryTimeout time.Duration // Request timeout of the retry pass (0 keeps the crawler's timeout)
+	FailedURLs   []string      // Pages that could not be fetched; after the retry pass, the ones that still failed
+	failed       []failedPage  // Nodes of FailedURLs, with the root they were found from
+
+	ctx     context.Context // Context of the running crawl; no new pages are fetched once it is done
+	stopErr error           // Why the crawl was stopped early, e.g. an exhausted token budget
+}
+
+// NewExplorerContext creates a new exploration context (without downloading content)
+func NewExplorerContext(rootURL string, maxDepth int, debug bool) (*HarvesterContext, error) {
+	return NewHarvesterContext(rootURL,
+		WithMaxDepth(maxDepth),
+		WithDebug(debug),
+		WithExploreMode(),
+	)
 }
 
 // NewDownloaderContext creates a new download context (actually downloads content)
 func NewDownloaderContext(rootURL string, outputFilePath string, baseURL string, maxDepth int, debug bool) (*HarvesterContext, error) {
-	// Create crawler
-	c := crawler.NewCrawler()
+	return NewXMLDownloaderContext(rootURL, outputFilePath, baseURL, maxDepth, debug)
+}
 
-	// Create web tree
-	webTree, err := tree.NewWebTree(rootURL, maxDepth)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create web tree: %w", err)
+// NewXMLDownloaderContext creates a download context using XML storage
+func NewXMLDownloaderContext(rootURL string, xmlFilePath string, baseURL string, maxDepth int, debug bool) (*HarvesterContext, error) {
+	return NewHarvesterContext(rootURL,
+		WithBaseURL(baseURL),
+		WithMaxDepth(maxDepth),
+		WithDebug(debug),
+		WithXMLStorage(xmlFilePath),
+	)
+}
+
+// Cleanup performs cleanup tasks, such as stopping auto-save.
+// Storages that write their output at the end (XML, consolidated Markdown) implement io.Closer.
+func (hc *HarvesterContext) Cleanup() {
+	if hc.totalTokens > 0 {
+		hc.Logger.Info("Estimated token count of saved pages", "tokens", hc.totalTokens)
 	}
 
-	// Create content extractor
-	e := extractor.NewContentExtractor()
+	if hc.BoilerplateShare > 0 {
+		if remover, ok := hc.Storage.(BoilerplateRemover); ok {
+			removed := remover.RemoveBoilerplate(hc.BoilerplateShare)
+			hc.Logger.Info("Removed boilerplate blocks", "blocks", removed)
+		} else {
+			hc.Logger.Warn("The storage can't remove boilerplate")
+		}
+	}
 
-	// Use XML storage instead of original LocalStorage
-	s, err := storage.NewXMLStorage(outputFilePath, rootURL)
+	if closer, ok := hc.Storage.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			hc.Logger.Error("Error saving output during cleanup", "error", err)
+		}
+	}
+}
+
+// AddSeedURL adds another starting URL to the crawl; it becomes an additional root of the web tree.
+// Seeds that are already part of the tree are ignored.
+func (hc *HarvesterContext) AddSeedURL(seedURL string) error {
+	rootNode, err := hc.WebTree.AddRoot(seedURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create XML storage: %w", err)
+		return fmt.Errorf("failed to add seed URL: %w", err)
+	}
+	if rootNode == nil {
+		return nil
 	}
 
-	return &HarvesterContext{
-		Crawler:     c,
-		WebTree:     webTree,
-		Extractor:   e,
-		Storage:     s,
-		RootURL:     rootURL,
-		BaseURL:     baseURL,
-		MaxDepth:    maxDepth,
-		Debug:       debug,
-		PrintedURLs: make(map[string]bool), // Initialize printed URLs map
-	}, nil
-}
+	// Record all seeds in the XML document
+	if xmlStorage, ok := hc.Storage.(*storage.XMLStorage); ok {
+		xmlStorage.Document.SeedURLs = hc.seedURLs()
+	}
 
-// NewXMLDownloaderContext creates a download context using XML storage
-func NewXMLDownloaderContext(rootURL string, xmlFilePath string, baseURL string, maxDepth int, debug bool) (*HarvesterContext, error) {
-	// Create crawler
-	c := crawler.NewCrawler()
+	return nil
+}
 
-	// Create web tree
-	webTree, err := tree.NewWebTree(rootURL, maxDepth)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create web tree: %w", err)
+// seedURLs returns the URLs of all roots of the web tree
+func (hc *HarvesterContext) seedURLs() []string {
+	var seeds []string
+	for _, root := range hc.WebTree.Roots {
+		seeds = append(seeds, root.URL.String())
 	}
+	return seeds
+}
 
-	// Create content extractor
-	e := extractor.NewContentExtractor()
+// inScope reports whether a link of a page below the root URL is within the crawl's Scope
+func (hc *HarvesterContext) inScope(rootURL string, link string) bool {
+	if hc.Scope == ScopeSubtree {
+		return isSubtreeURL(rootURL, link)
+	}
+	return hc.isParentURL(rootURL, link)
+}
 
-	// Create XML storage
-	s, err := storage.NewXMLStorage(xmlFilePath, rootURL)
+// isSubtreeURL reports whether a link is on the root URL's host, at or below the root's directory.
+// The directory of /docs/ and /docs is /docs/; only a last segment with an extension, like /docs/index.html, is a file within it.
+func isSubtreeURL(rootURL string, link string) bool {
+	currentURL, err := url.Parse(rootURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create XML storage: %w", err)
+		return false
 	}
 
-	return &HarvesterContext{
-		Crawler:     c,
-		WebTree:     webTree,
-		Extractor:   e,
-		Storage:     s,
-		RootURL:     rootURL,
-		BaseURL:     baseURL,
-		MaxDepth:    maxDepth,
-		Debug:       debug,
-		PrintedURLs: make(map[string]bool),
-	}, nil
-}
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
 
-// Cleanup performs cleanup tasks, such as stopping auto-save
-func (hc *HarvesterContext) Cleanup() {
-	// Check if it's XMLStorage
-	if xmlStorage, ok := hc.Storage.(*storage.XMLStorage); ok {
-		// Stop auto-save
-		xmlStorage.StopAutoSave()
+	if currentURL.Host != linkURL.Host {
+		return false
+	}
 
-		// Save one last time
-		if err := xmlStorage.SaveToFile(); err != nil {
-			fmt.Printf("Error saving XML file during cleanup: %v\n", err)
+	dir := currentURL.Path
+	if !strings.HasSuffix(dir, "/") {
+		if path.Ext(dir) != "" {
+			dir = path.Dir(dir)
 		}
+		dir = strings.TrimSuffix(dir, "/") + "/"
 	}
+
+	linkPath := linkURL.Path
+	if linkPath == "" {
+		linkPath = "/"
+	}
+	return strings.HasPrefix(linkPath, dir) || linkPath+"/" == dir
 }
 
-// isParentURL determines if a URL is a parent URL
-func (hc *HarvesterContext) isParentURL(link string) bool {
-	currentURL, err := url.Parse(hc.RootURL)
+// isParentURL determines if a URL is a parent URL of the given root URL
+func (hc *HarvesterContext) isParentURL(rootURL string, link string) bool {
+	currentURL, err := url.Parse(rootURL)
 	if err != nil {
 		return false
 	}
@@ -191,11 +333,7 @@ func (hc *HarvesterContext) isParentURL(link string) bool {
 	parentPath := currentPath[:lastSlash]
 
 	// Debug information
-	if hc.Debug {
-		fmt.Printf("Current path: %s\n", currentPath)
-		fmt.Printf("Parent path: %s\n", parentPath)
-		fmt.Printf("Link path: %s\n", linkPath)
-	}
+	hc.Logger.Debug("Checking parent URL", "current", currentPath, "parent", parentPath, "link", linkPath)
 
 	// Relaxed condition: Check if it's a parent path or contains parent path characteristics
 	if linkPath == parentPath {
@@ -210,6 +348,45 @@ func (hc *HarvesterContext) isParentURL(link string) bool {
 	return false
 }
 
+// isRepetitivePath reports whether a link's path repeats a segment more than MaxPathRepetition times.
+// Such paths usually come from crawl cycles that URL normalization can't catch, like relative links resolving ever deeper.
+func (hc *HarvesterContext) isRepetitivePath(link string) bool {
+	if hc.MaxPathRepetition <= 0 {
+		return false
+	}
+
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	counts := make(map[string]int)
+	for _, segment := range strings.Split(linkURL.Path, "/") {
+		if segment == "" {
+			continue
+		}
+		counts[segment]++
+		if counts[segment] > hc.MaxPathRepetition {
+			hc.Stats.skip("repetitive path")
+			hc.Logger.Debug("Filtered (repetitive path)", "url", link, "segment", segment)
+			return true
+		}
+	}
+
+	return false
+}
+
+// isQueryVariant reports whether a link differs only by its query string from a page already in the web tree,
+// in which case it is not followed unless query pages are included
+func (hc *HarvesterContext) isQueryVariant(link string) bool {
+	if !hc.WebTree.IsQueryVariant(link) {
+		return false
+	}
+	hc.Stats.skip("query variant")
+	hc.Logger.Debug("Filtered (query variant)", "url", link)
+	return true
+}
+
 // removeFragment removes the fragment part from a URL
 func (hc *HarvesterContext) removeFragment(linkStr string) string {
 	parsedURL, err := url.Parse(linkStr)
@@ -222,156 +399,1078 @@ func (hc *HarvesterContext) removeFragment(linkStr string) string {
 	return parsedURL.String()
 }
 
-// processLink processes a single link (exploration mode)
-func (hc *HarvesterContext) processLink(link string) {
-	// Only show parent URLs and remove fragments
-	if hc.isParentURL(link) {
-		cleanLink := hc.removeFragment(link)
+// extractLinks extracts the links of a page, leaving out rel="nofollow" links if they are respected
+func (hc *HarvesterContext) extractLinks(doc *html.Node, pageURL string) ([]string, error) {
+	baseURL := hc.linkBase(pageURL)
+	linkDetailer, ok := hc.Crawler.(LinkDetailer)
+	if !hc.RespectNofollow || !ok {
+		urls, err := hc.Crawler.ExtractLinks(doc, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		for i, u := range urls {
+			urls[i] = hc.fromLinkBase(u)
+		}
+		return urls, nil
+	}
 
-		// Check if URL has already been output
-		if !hc.PrintedURLs[cleanLink] {
-			fmt.Printf("<a href=\"%s\">\n", cleanLink)
-			// Mark as output
-			hc.PrintedURLs[cleanLink] = true
+	detailed, err := linkDetailer.ExtractLinksDetailed(doc, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, link := range detailed {
+		if link.IsNofollow() {
+			hc.Stats.skip("nofollow")
+			hc.Logger.Debug("Filtered (nofollow)", "url", link.URL)
+			continue
 		}
-	} else if hc.Debug {
-		// Filtered links, only show in debug mode
-		if hc.WebTree.IsVisited(link) {
-			fmt.Printf("Filtered (duplicated): %s\n", link)
-		} else {
-			fmt.Printf("Filtered (not parent): %s\n", link)
+		links = append(links, hc.fromLinkBase(link.URL))
+	}
+	return links, nil
+}
+
+// pageLinks returns the links of a page for storage; anchor text is only known if the fetcher provides it
+func (hc *HarvesterContext) pageLinks(doc *html.Node, pageURL string) []node.Link {
+	baseURL := hc.linkBase(pageURL)
+	links := []node.Link{}
+	if linkDetailer, ok := hc.Crawler.(LinkDetailer); ok {
+		detailed, err := linkDetailer.ExtractLinksDetailed(doc, baseURL)
+		if err != nil {
+			return links
+		}
+		for _, link := range detailed {
+			links = append(links, node.Link{URL: hc.fromLinkBase(link.URL), Text: link.Text})
 		}
+		return links
+	}
+
+	urls, err := hc.Crawler.ExtractLinks(doc, baseURL)
+	if err != nil {
+		return links
 	}
+	for _, u := range urls {
+		links = append(links, node.Link{URL: hc.fromLinkBase(u)})
+	}
+	return links
+}
+
+// linkBase returns the URL the relative links of a page resolve against.
+// With a BaseURL different from the root URL, a page below the root's directory resolves its links
+// at the same place below the base's directory, as if it had been fetched from there.
+func (hc *HarvesterContext) linkBase(pageURL string) string {
+	rootDir, baseDir, ok := hc.rebaseDirs()
+	if !ok || !strings.HasPrefix(pageURL, rootDir) {
+		return pageURL
+	}
+	return baseDir + strings.TrimPrefix(pageURL, rootDir)
+}
+
+// fromLinkBase maps a link below the base's directory back below the root's directory, where it is fetched from
+func (hc *HarvesterContext) fromLinkBase(link string) string {
+	rootDir, baseDir, ok := hc.rebaseDirs()
+	if !ok || !strings.HasPrefix(link, baseDir) {
+		return link
+	}
+	return rootDir + strings.TrimPrefix(link, baseDir)
+}
+
+// rebaseDirs returns the directories of the root URL and the base URL, if links are resolved against a different base
+func (hc *HarvesterContext) rebaseDirs() (rootDir string, baseDir string, ok bool) {
+	if hc.BaseURL == "" || hc.BaseURL == hc.RootURL {
+		return "", "", false
+	}
+	rootDir, rootOK := directoryURL(hc.RootURL)
+	baseDir, baseOK := directoryURL(hc.BaseURL)
+	return rootDir, baseDir, rootOK && baseOK && rootDir != baseDir
+}
+
+// directoryURL returns the URL of the directory containing a page, e.g. "https://example.org/docs/" for "https://example.org/docs/guide"
+func directoryURL(pageURL string) (string, bool) {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	return u.ResolveReference(&url.URL{Path: "./"}).String(), true
+}
+
+// isSelfLink reports whether a link points back to the page it was found on, such as an in-page "#section" anchor
+func (hc *HarvesterContext) isSelfLink(n *node.WebNode, link string) bool {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return n.IsAnchorOfSamePage(linkURL) || hc.removeFragment(link) == n.URLWithoutFragment()
+}
+
+// shouldFollow asks the OnLinkDiscovered hook whether a link should be processed
+func (hc *HarvesterContext) shouldFollow(link string) bool {
+	if hc.OnLinkDiscovered == nil {
+		return true
+	}
+	return hc.OnLinkDiscovered(link)
+}
+
+// reportError passes a page processing error to the OnError hook, or logs it if no hook is set
+func (hc *HarvesterContext) reportError(url string, err error) {
+	if hc.OnError != nil {
+		hc.OnError(url, err)
+		return
+	}
+	hc.Logger.Error("Failed to process page", "url", url, "error", err)
+}
+
+// fetchFailed reports a page found below rootNode that could not be fetched, records the failure in the output
+// and adds it to FailedURLs; rootNode is nil for pages whose links are not followed, like next pages.
+// Pages skipped because of their content type or robots.txt are only logged.
+func (hc *HarvesterContext) fetchFailed(rootNode *node.WebNode, n *node.WebNode, err error) {
+	if errors.Is(err, crawler.ErrSkippedContentType) {
+		hc.Stats.skip("content type")
+		hc.Logger.Info("Skipped (content type)", "url", n.URLWithoutFragment(), "reason", err)
+		return
+	}
+	if errors.Is(err, crawler.ErrRobotsDisallowed) {
+		hc.Stats.skip("robots.txt")
+		hc.Logger.Info("Skipped (robots.txt)", "url", n.URLWithoutFragment())
+		return
+	}
+
+	if hc.OnError != nil {
+		hc.OnError(n.URLWithoutFragment(), err)
+	} else {
+		attrs := []any{"url", n.URLWithoutFragment(), "depth", n.Depth}
+		var statusErr *crawler.StatusError
+		if errors.As(err, &statusErr) {
+			attrs = append(attrs, "status", statusErr.StatusCode)
+		}
+		hc.Logger.Error("Failed to fetch page", append(attrs, "error", err)...)
+	}
+	hc.failed = append(hc.failed, failedPage{root: rootNode, node: n})
+	hc.FailedURLs = append(hc.FailedURLs, n.URLWithoutFragment())
+
+	n.Metadata["error"] = err.Error()
+	if err := hc.Storage.SaveNodeContent(n, ""); err != nil {
+		hc.Logger.Debug("Failed to record fetch error", "url", n.URLWithoutFragment(), "error", err)
+	}
+}
+
+// logFiltered logs a link that was not followed
+func (hc *HarvesterContext) logFiltered(link string) {
+	if hc.WebTree.IsVisited(link) {
+		hc.Stats.skip("visited")
+		hc.Logger.Debug("Filtered (duplicated)", "url", link)
+	} else if hc.Scope == ScopeSubtree {
+		hc.Stats.skip("out of scope")
+		hc.Logger.Debug("Filtered (out of scope)", "url", link)
+	} else {
+		hc.Stats.skip("not parent")
+		hc.Logger.Debug("Filtered (not parent)", "url", link)
+	}
+}
+
+// processLink processes a single link found on a root page (exploration mode).
+// Parent URLs not seen before are added to the web tree and returned; otherwise an empty string is returned.
+func (hc *HarvesterContext) processLink(rootNode *node.WebNode, link string) string {
+	// Only keep parent URLs and remove fragments
+	if !hc.inScope(rootNode.URL.String(), link) {
+		// Filtered links, only shown in debug mode
+		hc.logFiltered(link)
+		return ""
+	}
+	if hc.isRepetitivePath(link) || hc.skipDownloadLink(link) || hc.isQueryVariant(link) {
+		return ""
+	}
+
+	cleanLink := hc.removeFragment(link)
+
+	// Check if URL has already been discovered
+	if !hc.PrintedURLs.Add(cleanLink) {
+		return ""
+	}
+
+	if _, err := hc.WebTree.AddURL(cleanLink, rootNode); err != nil {
+		hc.Logger.Debug("Failed to add URL to tree", "url", cleanLink, "error", err)
+	}
+
+	return cleanLink
+}
+
+// linkLimitReached reports whether MaxLinksPerPage links of a page have been accepted
+func (hc *HarvesterContext) linkLimitReached(n *node.WebNode, accepted int) bool {
+	if hc.MaxLinksPerPage <= 0 || accepted < hc.MaxLinksPerPage {
+		return false
+	}
+	hc.Logger.Debug("Reached the link limit of the page", "url", n.URLWithoutFragment(), "limit", hc.MaxLinksPerPage)
+	return true
+}
+
+// stopped reports whether the running crawl must not fetch any more pages
+func (hc *HarvesterContext) stopped() bool {
+	return hc.stopReason() != nil
 }
 
-// Explore explores the website structure without downloading content
-func (hc *HarvesterContext) Explore() error {
+// stopReason returns why the running crawl was stopped, or nil if it was not
+func (hc *HarvesterContext) stopReason() error {
+	if hc.stopErr != nil {
+		return hc.stopErr
+	}
+	if hc.MaxPages > 0 && hc.Stats.PagesFetched >= hc.MaxPages {
+		return fmt.Errorf("page limit of %d reached", hc.MaxPages)
+	}
+	if hc.ctx != nil {
+		return hc.ctx.Err()
+	}
+	return nil
+}
+
+// Explore explores the website structure without downloading content.
+// Discovered parent URLs of every seed are added to the web tree and returned in discovery order.
+func (hc *HarvesterContext) Explore() ([]string, error) {
+	return hc.ExploreContext(context.Background())
+}
+
+// ExploreContext is like Explore, but stops fetching seed pages once ctx is done.
+// The URLs discovered until then are returned without an error.
+func (hc *HarvesterContext) ExploreContext(ctx context.Context) ([]string, error) {
+	hc.ctx = ctx
+	defer func() { hc.ctx = nil }()
+
+	var discovered []string
+	for _, rootNode := range hc.WebTree.Roots {
+		if hc.stopped() {
+			hc.Logger.Warn("Exploration stopped before completion", "reason", hc.stopReason())
+			break
+		}
+		links, err := hc.exploreFrom(rootNode)
+		if err != nil {
+			return nil, err
+		}
+		discovered = append(discovered, links...)
+	}
+
+	return discovered, nil
+}
+
+// exploreFrom explores the links found on a single root page
+func (hc *HarvesterContext) exploreFrom(rootNode *node.WebNode) ([]string, error) {
+	rootURL := rootNode.URL.String()
+
 	// Get the HTML content of the initial page
-	doc, err := hc.Crawler.FetchPage(hc.RootURL)
+	doc, _, err := hc.fetch(rootURL)
 	if err != nil {
-		return fmt.Errorf("failed to fetch the URL: %w", err)
+		return nil, fmt.Errorf("failed to fetch the URL: %w", err)
 	}
 
 	// Extract title
-	title := hc.Crawler.ExtractTitle(doc)
-	rootNode := hc.WebTree.RootNode
-	rootNode.Title = title
+	rootNode.Title = hc.Crawler.ExtractTitle(doc)
 
 	// Extract all links
-	links, err := hc.Crawler.ExtractLinks(doc, hc.RootURL)
+	links, err := hc.extractLinks(doc, rootURL)
 	if err != nil {
-		return fmt.Errorf("failed to extract links: %w", err)
+		return nil, fmt.Errorf("failed to extract links: %w", err)
 	}
 
 	// Process each link
+	var discovered []string
 	for _, link := range links {
-		hc.processLink(link)
+		if hc.linkLimitReached(rootNode, len(discovered)) {
+			break
+		}
+		if hc.isSelfLink(rootNode, link) || !hc.shouldFollow(link) {
+			continue
+		}
+		if cleanLink := hc.processLink(rootNode, link); cleanLink != "" {
+			discovered = append(discovered, cleanLink)
+		}
 	}
 
-	return nil
+	return discovered, nil
 }
 
-// Download downloads website content
+// Download downloads website content, starting from every seed URL
 func (hc *HarvesterContext) Download() error {
-	fmt.Printf("Downloading content from URL: %s\n", hc.RootURL)
+	return hc.DownloadContext(context.Background())
+}
+
+// DownloadContext is like Download, but stops fetching new pages once ctx is done,
+// e.g. when a time budget runs out. Pages harvested until then are kept and no error is returned.
+func (hc *HarvesterContext) DownloadContext(ctx context.Context) error {
+	hc.ctx = ctx
+	defer func() { hc.ctx = nil }()
+
+	if hc.Stats.StartedAt.IsZero() {
+		hc.Stats.StartedAt = time.Now()
+	}
+	defer func() { hc.Stats.ElapsedSeconds = time.Since(hc.Stats.StartedAt).Seconds() }()
+
+	for _, rootNode := range hc.WebTree.Roots {
+		if hc.stopped() {
+			break
+		}
+		if err := hc.downloadFrom(rootNode); err != nil {
+			return err
+		}
+	}
+
+	if hc.RetryFailed && len(hc.failed) > 0 && !hc.stopped() {
+		hc.retryFailed()
+	}
+
+	if hc.stopped() {
+		hc.Logger.Warn("Download stopped before completion", "reason", hc.stopReason())
+	}
+
+	return nil
+}
+
+// downloadFrom downloads a root page and, in CrawlOrder, the pages it links to within the depth limit
+func (hc *HarvesterContext) downloadFrom(rootNode *node.WebNode) error {
+	rootURL := rootNode.URL.String()
+	hc.Logger.Info("Downloading content", "url", rootURL)
 
 	// Get the HTML content of the initial page
-	doc, err := hc.Crawler.FetchPage(hc.RootURL)
+	doc, header, err := hc.fetchNode(rootNode)
 	if err != nil {
 		return fmt.Errorf("failed to fetch the URL: %w", err)
 	}
 
+	// Extract and save content
+	nextPage := hc.nextPageURL(rootNode, doc)
+	if err := hc.harvestPage(rootNode, doc, header); err != nil {
+		return err
+	}
+	if hc.SinglePage {
+		return nil
+	}
+	hc.followPagination(rootNode, nextPage)
+
+	// Download the linked pages in crawl order
+	hc.crawl(rootNode, hc.discoverLinks(rootNode, rootNode, doc))
+
+	// Create index file
+	if rootNode.URL != nil {
+		indexPath := rootNode.URL.Path
+		if err := hc.Storage.CreateIndexFile(indexPath); err != nil {
+			hc.Logger.Debug("Failed to create index file", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// crawl downloads the pages found below rootNode and the pages they link to, in CrawlOrder.
+// Pages waiting to be fetched are kept in the web tree's frontier store.
+func (hc *HarvesterContext) crawl(rootNode *node.WebNode, found []*node.WebNode) {
+	hc.enqueue(found)
+	if hc.MaxConcurrency > 1 {
+		hc.crawlConcurrently(rootNode)
+		return
+	}
+
+	for !hc.stopped() {
+		n := hc.dequeue()
+		if n == nil {
+			break
+		}
+
+		doc, header, err := hc.fetchNode(n)
+		if err != nil {
+			hc.fetchFailed(rootNode, n, err)
+			continue
+		}
+
+		hc.enqueue(hc.downloadPage(rootNode, n, doc, header))
+	}
+}
+
+// dequeue takes the page to fetch next from the frontier: the oldest one breadth-first, the newest one depth-first.
+// It returns nil when no page is pending, or when the frontier store fails, which stops the crawl.
+func (hc *HarvesterContext) dequeue() *node.WebNode {
+	n, err := hc.WebTree.Frontier.Pop(hc.CrawlOrder == CrawlOrderDFS)
+	if err != nil {
+		hc.frontierFailed(err)
+		return nil
+	}
+	return n
+}
+
+// enqueue adds the pages linked from a page to the frontier.
+// Depth-first, they are added in reverse, so they are still fetched in the order they appear on the page.
+func (hc *HarvesterContext) enqueue(found []*node.WebNode) {
+	for i := range found {
+		n := found[i]
+		if hc.CrawlOrder == CrawlOrderDFS {
+			n = found[len(found)-1-i]
+		}
+		if err := hc.WebTree.Frontier.Push(n); err != nil {
+			hc.frontierFailed(err)
+			return
+		}
+	}
+}
+
+// frontierFailed stops the crawl because the frontier store failed, e.g. on a full disk
+func (hc *HarvesterContext) frontierFailed(err error) {
+	if hc.stopErr == nil {
+		hc.stopErr = fmt.Errorf("crawl frontier: %w", err)
+	}
+}
+
+// downloadPage harvests a fetched page and follows its pagination, returning the new nodes of the links to download next
+func (hc *HarvesterContext) downloadPage(rootNode *node.WebNode, n *node.WebNode, doc *html.Node, header http.Header) []*node.WebNode {
+	nextPage := hc.nextPageURL(n, doc)
+	if err := hc.harvestPage(n, doc, header); err != nil {
+		hc.reportError(n.URL.String(), err)
+		return nil
+	}
+	hc.followPagination(n, nextPage)
+
+	return hc.discoverLinks(rootNode, n, doc)
+}
+
+// failedPage is a page that could not be fetched, kept for the retry pass
+type failedPage struct {
+	root *node.WebNode // Root the page was found from; nil if its links are not followed
+	node *node.WebNode
+}
+
+// retryFailed fetches the pages that failed once more, with RetryTimeout as the request timeout.
+// Recovered pages are harvested and their links followed; FailedURLs is left with the pages that still failed.
+func (hc *HarvesterContext) retryFailed() {
+	defer hc.useRetryTimeout()()
+
+	pages := hc.failed
+	hc.failed, hc.FailedURLs = nil, nil
+	hc.Logger.Info("Retrying failed pages", "count", len(pages))
+
+	for i, page := range pages {
+		if hc.stopped() {
+			// Pages not retried still count as failed
+			for _, remaining := range pages[i:] {
+				hc.failed = append(hc.failed, remaining)
+				hc.FailedURLs = append(hc.FailedURLs, remaining.node.URLWithoutFragment())
+			}
+			break
+		}
+
+		hc.Stats.Retried++
+		doc, header, err := hc.fetchNode(page.node)
+		if err != nil {
+			hc.fetchFailed(page.root, page.node, err)
+			continue
+		}
+
+		hc.Stats.Recovered++
+		delete(page.node.Metadata, "error")
+		if page.root == nil {
+			if err := hc.harvestPage(page.node, doc, header); err != nil {
+				hc.reportError(page.node.URLWithoutFragment(), err)
+			}
+			continue
+		}
+		hc.crawl(page.root, hc.downloadPage(page.root, page.node, doc, header))
+	}
+
+	for _, u := range hc.FailedURLs {
+		hc.Logger.Warn("Still failing after retry", "url", u)
+	}
+}
+
+// RetryPagesContext fetches the given pages once more, like the failures recorded in an earlier harvest,
+// and saves them in place of the failed entries; their links are not followed. Requests use RetryTimeout.
+// It returns the number of pages recovered; the pages that fail again are recorded as failures and listed in FailedURLs.
+func (hc *HarvesterContext) RetryPagesContext(ctx context.Context, urls []string) int {
+	hc.ctx = ctx
+	defer func() { hc.ctx = nil }()
+
+	if hc.Stats.StartedAt.IsZero() {
+		hc.Stats.StartedAt = time.Now()
+	}
+	defer func() { hc.Stats.ElapsedSeconds = time.Since(hc.Stats.StartedAt).Seconds() }()
+	defer hc.useRetryTimeout()()
+
+	hc.Logger.Info("Retrying failed pages", "count", len(urls))
+	recovered := 0
+	for _, u := range urls {
+		if hc.stopped() {
+			hc.Logger.Warn("Retry stopped before completion", "reason", hc.stopReason())
+			break
+		}
+
+		n, err := node.NewWebNode(u, nil)
+		if err != nil {
+			hc.reportError(u, err)
+			continue
+		}
+
+		hc.Stats.Retried++
+		doc, header, err := hc.fetchNode(n)
+		if err != nil {
+			hc.fetchFailed(nil, n, err)
+			continue
+		}
+
+		hc.Stats.Recovered++
+		recovered++
+		if err := hc.harvestPage(n, doc, header); err != nil {
+			hc.reportError(u, err)
+		}
+	}
+
+	return recovered
+}
+
+// useRetryTimeout sets the request timeout of the HTTP crawler to RetryTimeout, if set.
+// The returned function restores the previous timeout.
+func (hc *HarvesterContext) useRetryTimeout() func() {
+	c := hc.httpCrawler()
+	if c == nil || hc.RetryTimeout <= 0 {
+		return func() {}
+	}
+	timeout := c.RequestTimeout
+	c.RequestTimeout = hc.RetryTimeout
+	return func() { c.RequestTimeout = timeout }
+}
+
+// httpCrawler returns the *crawler.Crawler making the requests of the page fetcher, or nil for other fetchers
+func (hc *HarvesterContext) httpCrawler() *crawler.Crawler {
+	switch c := hc.Crawler.(type) {
+	case *crawler.Crawler:
+		return c
+	case *crawler.Renderer:
+		return c.Crawler
+	}
+	return nil
+}
+
+// discoverLinks extracts the links of a downloaded page and adds the ones to download to the web tree.
+// The new nodes are returned; links of pages at the depth limit or marked nofollow are not followed.
+func (hc *HarvesterContext) discoverLinks(rootNode *node.WebNode, n *node.WebNode, doc *html.Node) []*node.WebNode {
+	if !hc.WebTree.IsAllowedDepth(n.Depth+1) || hc.noFollow(n) {
+		return nil
+	}
+	if hops := externalHops(n); hops > 0 && hops >= hc.FollowExternalDepth {
+		hc.Logger.Debug("Not following links of external page", "url", n.URLWithoutFragment(), "hops", hops)
+		return nil
+	}
+
+	links, err := hc.extractLinks(doc, n.URL.String())
+	if err != nil {
+		hc.reportError(n.URL.String(), fmt.Errorf("failed to extract links: %w", err))
+		return nil
+	}
+
+	hc.Logger.Info("Found links on the page", "url", n.URLWithoutFragment(), "depth", n.Depth, "count", len(links))
+	hc.Stats.LinksDiscovered += len(links)
+
+	var found []*node.WebNode
+	for _, link := range links {
+		if hc.stopped() || hc.linkLimitReached(n, len(found)) {
+			break
+		}
+		if hc.isSelfLink(n, link) || !hc.shouldFollow(link) {
+			continue
+		}
+		if child := hc.processLinkAndDownload(rootNode, n, link); child != nil {
+			found = append(found, child)
+		}
+	}
+
+	return found
+}
+
+// pageMetadataKeys lists the <meta> names and properties stored with each page
+var pageMetadataKeys = []string{"description", "author", "og:title", "og:description", "og:image"}
+
+// fetch fetches and parses a page, along with its response headers if the page fetcher provides them.
+// Fetches are counted in Stats.
+func (hc *HarvesterContext) fetch(urlStr string) (*html.Node, http.Header, error) {
+	result := hc.fetchResponse(urlStr)
+	hc.countFetch(result)
+	return result.doc, result.header, result.err
+}
+
+// fetchNode is fetch for a page of the web tree, also recording how long the fetch took
+func (hc *HarvesterContext) fetchNode(n *node.WebNode) (*html.Node, http.Header, error) {
+	result := hc.fetchResponse(n.URL.String())
+	result.node = n
+	hc.countFetch(result)
+	return result.doc, result.header, result.err
+}
+
+// fetchResult is a fetched page, or why it could not be fetched
+type fetchResult struct {
+	node     *node.WebNode // Page of the web tree, if the fetch was for one
+	doc      *html.Node
+	header   http.Header
+	body     []byte // Body the document was parsed from, if the fetcher returns it
+	size     int64
+	cached   bool // Whether the page came from the page cache
+	duration time.Duration
+	err      error
+}
+
+// fetchResponse fetches and parses a page without touching the context's state, so it can run in parallel
+func (hc *HarvesterContext) fetchResponse(urlStr string) fetchResult {
+	var result fetchResult
+	start := time.Now()
+	if responseFetcher, ok := hc.Crawler.(ResponseFetcher); ok {
+		var page *crawler.Page
+		if page, result.err = responseFetcher.Fetch(urlStr); result.err == nil {
+			result.doc, result.header, result.body, result.size = page.Doc, page.Header, page.Body, page.Size
+			result.cached = page.Cached
+			if page.DeclaredCharset != "" {
+				hc.Logger.Warn("Declared charset does not match the content; decoded with a detected charset",
+					"url", urlStr, "declared", page.DeclaredCharset, "charset", page.Charset)
+			}
+		}
+	} else {
+		result.doc, result.err = hc.Crawler.FetchPage(urlStr)
+	}
+	result.duration = time.Since(start)
+	return result
+}
+
+// countFetch counts a fetch in Stats and records its duration with the page
+func (hc *HarvesterContext) countFetch(result fetchResult) {
+	hc.Stats.FetchSeconds += result.duration.Seconds()
+	if result.node != nil {
+		result.node.Metadata["fetchMs"] = strconv.FormatInt(result.duration.Milliseconds(), 10)
+		if hc.DumpRaw && result.body != nil {
+			result.node.Metadata["rawContent"] = string(result.body)
+		}
+	}
+
+	switch {
+	case errors.Is(result.err, crawler.ErrSkippedContentType), errors.Is(result.err, crawler.ErrRobotsDisallowed):
+		// Counted as skipped by fetchFailed
+	case result.err != nil:
+		hc.Stats.fail(result.err)
+	case result.cached:
+		hc.Stats.PagesFetched++
+		hc.Stats.CacheHits++
+	default:
+		hc.Stats.PagesFetched++
+		hc.Stats.Bytes += result.size
+	}
+}
+
+// harvestPage extracts the title, content and content statistics of a fetched page and saves them
+func (hc *HarvesterContext) harvestPage(n *node.WebNode, doc *html.Node, header http.Header) error {
+	start := time.Now()
+
+	// The raw HTML is only needed until the page is saved; the tree keeps the node for the rest of the crawl
+	defer delete(n.Metadata, "rawContent")
+
 	// Extract title
-	title := hc.Crawler.ExtractTitle(doc)
-	rootNode := hc.WebTree.RootNode
-	rootNode.Title = title
+	n.Title = hc.Crawler.ExtractTitle(doc)
+
+	// Skip pages not modified since the given time; pages without Last-Modified are always harvested
+	if !hc.Since.IsZero() {
+		if modified, err := http.ParseTime(header.Get("Last-Modified")); err == nil && modified.Before(hc.Since) {
+			hc.Stats.skip("not modified")
+			hc.Logger.Debug("Skipped (not modified)", "url", n.URLWithoutFragment(), "lastModified", modified)
+			return nil
+		}
+	}
+
+	// Honor noindex; nofollow is recorded for the callers following links from this page
+	if hc.RespectRobotsMeta {
+		directives := hc.robotsDirectives(doc, header)
+		if source, ok := directives["nofollow"]; ok {
+			n.Metadata["nofollow"] = source
+		}
+		if source, ok := directives["noindex"]; ok {
+			hc.Stats.skip("noindex")
+			hc.Logger.Debug("Skipped (noindex)", "url", n.URLWithoutFragment(), "source", source)
+			return nil
+		}
+	}
+
+	// Record the links of the page with their anchor text, before navigation is stripped
+	n.Links = hc.pageLinks(doc, n.URLWithoutFragment())
+
+	// Extract description, author and OpenGraph metadata
+	metadata := hc.Extractor.ExtractMetadata(doc)
+	for _, key := range pageMetadataKeys {
+		if value := strings.TrimSpace(metadata[key]); value != "" {
+			n.Metadata[key] = value
+		}
+	}
+
+	// Record the publication and update dates shown on the page
+	if published, updated, ok := hc.pageTools().ExtractDates(doc); ok {
+		if !published.IsZero() {
+			n.Metadata["publishedAt"] = published.Format(time.RFC3339)
+		}
+		if !updated.IsZero() {
+			n.Metadata["updatedAt"] = updated.Format(time.RFC3339)
+		}
+	}
+
+	// Pages declaring a canonical URL are stored under it; a canonical URL seen before means this page is an alias
+	if canonical := hc.pageTools().ExtractCanonicalURL(doc, n.URL); canonical != "" && canonical != n.URLWithoutFragment() {
+		n.Metadata["canonical"] = canonical
+		alreadyVisited, err := hc.WebTree.MarkVisited(canonical)
+		if err == nil && alreadyVisited {
+			hc.Stats.skip("canonical")
+			hc.Logger.Debug("Skipped (duplicate of canonical URL)", "url", n.URLWithoutFragment(), "canonical", canonical)
+			return nil
+		}
+	}
+
+	// Client-rendered pages come back nearly empty; say why instead of silently storing them
+	if hc.pageTools().NeedsJavaScript(doc) {
+		n.Metadata["requiresJs"] = "true"
+		hc.Logger.Warn("Page may require JavaScript rendering", "url", n.URLWithoutFragment())
+	}
+
+	// Make links and images in the stored content independent of the page location
+	base := n.URL
+	if linkBase, err := url.Parse(hc.linkBase(n.URL.String())); err == nil {
+		base = linkBase
+	}
+	hc.pageTools().AbsolutizeURLs(doc, base)
 
 	// Extract content
-	content, err := hc.Extractor.ExtractContent(doc)
+	content, strategy, err := hc.extractContent(doc)
 	if err != nil {
 		return fmt.Errorf("failed to extract content: %w", err)
 	}
+	if strategy != "" {
+		n.Metadata["extraction"] = strategy
+	}
+
+	// Transform and cap the content before statistics are taken, so they describe what is stored
+	if hc.ContentTransformer != nil {
+		content = hc.ContentTransformer(content)
+	}
+	if hc.MaxContentChars > 0 {
+		var truncated bool
+		content, truncated = hc.pageTools().TruncateHTML(content, hc.MaxContentChars)
+		if truncated {
+			n.Metadata["truncated"] = "true"
+		}
+	}
+
+	// Record word count and reading time
+	text := hc.pageTools().PlainText(content)
+	words, readMinutes := hc.pageTools().Stats(text)
+	n.Metadata["wordCount"] = strconv.Itoa(words)
+	n.Metadata["readingTime"] = strconv.Itoa(readMinutes)
+	estimate := hc.TokenEstimator
+	if estimate == nil {
+		estimate = storage.EstimateTokens
+	}
+	tokens := estimate(text)
+	n.Metadata["tokens"] = strconv.Itoa(tokens)
+
+	// Record a short excerpt for previews and the heading anchors for deep links
+	if contentDoc, err := html.Parse(strings.NewReader(content)); err == nil {
+		if hc.ExcerptChars > 0 {
+			if excerpt := hc.pageTools().Excerpt(contentDoc, hc.ExcerptChars); excerpt != "" {
+				n.Metadata["excerpt"] = excerpt
+			}
+		}
+		n.Anchors = nil
+		for _, anchor := range hc.pageTools().ExtractAnchors(contentDoc) {
+			n.Anchors = append(n.Anchors, node.Anchor{ID: anchor.ID, Text: anchor.Text})
+		}
+	}
+
+	// Detect page language
+	lang := hc.pageTools().DetectLanguage(doc, text)
+	n.Metadata["lang"] = lang
+	if hc.OnlyLang != "" && lang != extractor.NormalizeLanguage(hc.OnlyLang) {
+		hc.Stats.skip("language")
+		hc.Logger.Debug("Skipped (language)", "url", n.URLWithoutFragment(), "lang", lang)
+		return nil
+	}
+
+	// Skip redirect shells and empty templates; their links are still followed
+	if chars := utf8.RuneCountInString(strings.TrimSpace(text)); hc.PruneEmpty && chars < hc.MinContentChars {
+		hc.Stats.skip("empty")
+		hc.Logger.Debug("Skipped (empty)", "url", n.URLWithoutFragment(), "chars", chars)
+		return nil
+	}
+
+	// Download images so the stored content works offline
+	if hc.DownloadAssets {
+		content = hc.pageTools().RewriteImages(content, func(src string) string {
+			return hc.localizeAsset(n, src)
+		})
+	}
+
+	// Catalog links to files such as PDFs
+	hc.collectDownloads(n)
+
+	hc.recordExtractTime(n, time.Since(start))
+
+	if hc.OnPageFetched != nil {
+		hc.OnPageFetched(n, content)
+	}
+
+	// Stop instead of saving a page that would outgrow the output size budget
+	if hc.MaxOutputSize > 0 && hc.outputSize+int64(len(content)) > hc.MaxOutputSize {
+		hc.Stats.skip("output size")
+		if hc.stopErr == nil {
+			hc.stopErr = fmt.Errorf("output size budget of %d bytes reached (%d bytes saved)", hc.MaxOutputSize, hc.outputSize)
+		}
+		return nil
+	}
 
 	// Save content
-	if err := hc.Storage.SaveNodeContent(rootNode, content); err != nil {
+	if err := hc.Storage.SaveNodeContent(n, content); err != nil {
 		return fmt.Errorf("failed to save content: %w", err)
 	}
+	hc.Stats.PagesSaved++
+	hc.outputSize += int64(len(content))
 
-	// Extract all links
-	links, err := hc.Crawler.ExtractLinks(doc, hc.RootURL)
+	// Stop fetching once the token budget is used up
+	hc.totalTokens += tokens
+	if hc.MaxTokens > 0 && hc.totalTokens >= hc.MaxTokens && hc.stopErr == nil {
+		hc.stopErr = fmt.Errorf("token budget of %d reached", hc.MaxTokens)
+	}
+
+	return nil
+}
+
+// extractContent extracts the content of a page, along with the extraction strategy used if it is known.
+// The default extractor targets the main content only when a content selector or extraction strategies are configured;
+// other extractors are expected to find the main content themselves.
+func (hc *HarvesterContext) extractContent(doc *html.Node) (string, string, error) {
+	contentExtractor, ok := hc.Extractor.(*extractor.ContentExtractor)
+	if !ok {
+		content, err := hc.Extractor.ExtractMainContent(doc)
+		return content, "", err
+	}
+	if contentExtractor.ContentSelector == "" && contentExtractor.Strategies == nil {
+		content, err := contentExtractor.ExtractContent(doc)
+		return content, extractor.StrategyBody, err
+	}
+	return contentExtractor.ExtractWithStrategy(doc)
+}
+
+// defaultPageTools provides the HTML helpers of pages when a custom Extractor is used
+var defaultPageTools = extractor.NewContentExtractor()
+
+// pageTools returns the helpers for links, images, text statistics and language of pages,
+// which are shared by all extractors
+func (hc *HarvesterContext) pageTools() *extractor.ContentExtractor {
+	if contentExtractor, ok := hc.Extractor.(*extractor.ContentExtractor); ok {
+		return contentExtractor
+	}
+	return defaultPageTools
+}
+
+// recordExtractTime adds the extraction time of a page to Stats and logs the page if fetching and extracting it
+// took longer than SlowThreshold
+func (hc *HarvesterContext) recordExtractTime(n *node.WebNode, extract time.Duration) {
+	hc.Stats.ExtractSeconds += extract.Seconds()
+
+	fetchMs, _ := strconv.ParseInt(n.Metadata["fetchMs"], 10, 64)
+	fetch := time.Duration(fetchMs) * time.Millisecond
+	if hc.SlowThreshold > 0 && fetch+extract > hc.SlowThreshold {
+		hc.Stats.SlowPages++
+		hc.Logger.Warn("Slow page", "url", n.URLWithoutFragment(), "fetch", fetch, "extract", extract.Round(time.Millisecond))
+	}
+}
+
+// localizeAsset downloads an image referenced by a page and returns the reference to store instead.
+// The original reference is kept for data URIs, external hosts (unless allowed) and failed downloads.
+func (hc *HarvesterContext) localizeAsset(n *node.WebNode, src string) string {
+	if strings.HasPrefix(src, "data:") {
+		return src
+	}
+
+	assetURL, err := url.Parse(src)
+	if err != nil || (assetURL.Scheme != "http" && assetURL.Scheme != "https") {
+		return src
+	}
+
+	if n.URL != nil && assetURL.Host != n.URL.Host && !hc.AllowExternalAssets {
+		hc.Logger.Debug("Skipped external asset", "url", src)
+		return src
+	}
+
+	if hc.assetCache == nil {
+		hc.assetCache = make(map[string]string)
+	}
+	if ref, ok := hc.assetCache[src]; ok {
+		return ref
+	}
+
+	assetFetcher, ok := hc.Crawler.(AssetFetcher)
+	if !ok {
+		return src
+	}
+
+	data, contentType, err := assetFetcher.FetchAsset(src, hc.MaxAssetSize)
 	if err != nil {
-		return fmt.Errorf("failed to extract links: %w", err)
+		hc.Logger.Warn("Failed to download asset", "url", src, "error", err)
+		hc.assetCache[src] = src
+		return src
 	}
 
-	fmt.Printf("Found %d links on the page.\n", len(links))
+	// Assets are stored as files referenced by relative path if requested, and embedded as data URIs otherwise
+	ref := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	if fileStorage, ok := hc.Storage.(FileStorage); ok && hc.AssetFiles {
+		ref, err = fileStorage.SaveFile(path.Base(assetURL.Path), data)
+		if err != nil {
+			hc.Logger.Warn("Failed to store asset", "url", src, "error", err)
+			ref = src
+		}
+	}
+	hc.assetCache[src] = ref
+	return ref
+}
 
-	// Process each link
-	for _, link := range links {
-		hc.processLinkAndDownload(link)
+// processLinkAndDownload processes a single link found on a page below rootNode (download mode).
+// Parent URLs of the root not seen before are added to the web tree below the page and returned for downloading.
+func (hc *HarvesterContext) processLinkAndDownload(rootNode *node.WebNode, parent *node.WebNode, link string) *node.WebNode {
+	// Only process parent URLs, or off-host links within the external depth budget
+	hops := externalHops(parent)
+	external := hops > 0 || !hc.inScope(rootNode.URL.String(), link)
+	if external && (hops >= hc.FollowExternalDepth || !isExternalLink(rootNode, link)) {
+		// Filtered links, only shown in debug mode
+		hc.logFiltered(link)
+		return nil
+	}
+	if hc.isRepetitivePath(link) || hc.skipDownloadLink(link) || hc.isQueryVariant(link) {
+		return nil
 	}
 
-	// Create index file
-	if rootNode.URL != nil {
-		indexPath := rootNode.URL.Path
-		if err := hc.Storage.CreateIndexFile(indexPath); err != nil && hc.Debug {
-			fmt.Printf("Failed to create index file: %s\n", err)
+	cleanLink := hc.removeFragment(link)
+
+	// Output each URL once
+	if hc.PrintedURLs.Add(cleanLink) {
+		if external {
+			hc.Logger.Info("Found external URL", "url", cleanLink, "depth", parent.Depth+1, "hops", hops+1)
+		} else {
+			hc.Logger.Info("Found parent URL", "url", cleanLink, "depth", parent.Depth+1)
 		}
 	}
 
-	return nil
+	// Only download pages if download all pages is enabled
+	if !hc.DownloadAll {
+		return nil
+	}
+
+	// Add link below the page; already visited URLs (including other seeds) are skipped
+	child, err := hc.WebTree.AddURL(link, parent)
+	if err != nil {
+		hc.Logger.Debug("Skipping invalid URL", "url", link, "error", err)
+		hc.Stats.skip("invalid URL")
+		return nil
+	}
+	if child == nil || child.URL == nil {
+		hc.Stats.skip("visited")
+		return nil
+	}
+	if external {
+		child.Metadata["externalHops"] = strconv.Itoa(hops + 1)
+	}
+
+	return child
+}
+
+// externalHops returns how many links away from the crawled site an off-host page is; 0 for pages of the site
+func externalHops(n *node.WebNode) int {
+	hops, _ := strconv.Atoi(n.Metadata["externalHops"])
+	return hops
 }
 
-// processLinkAndDownload processes a single link and downloads it (download mode)
-func (hc *HarvesterContext) processLinkAndDownload(link string) {
-	// Only process parent URLs
-	if hc.isParentURL(link) {
-		cleanLink := hc.removeFragment(link)
-
-		// Check if URL has already been output
-		if !hc.PrintedURLs[cleanLink] {
-			fmt.Printf("<a href=\"%s\">\n", cleanLink)
-			// Mark as output
-			hc.PrintedURLs[cleanLink] = true
-		}
-
-		// If download all pages is enabled
-		if hc.DownloadAll {
-			// Parse link
-			parsedURL := hc.WebTree.FindNode(hc.RootURL)
-			parsedLink, _ := hc.WebTree.AddURL(link, parsedURL)
-
-			if parsedLink != nil && parsedLink.URL != nil {
-				// Get page content
-				doc, err := hc.Crawler.FetchPage(parsedLink.URL.String())
-				if err != nil {
-					fmt.Printf("Failed to fetch: %s - %s\n", parsedLink.URL.String(), err)
-					return
-				}
-
-				// Extract title
-				title := hc.Crawler.ExtractTitle(doc)
-				parsedLink.Title = title
-
-				// Extract content
-				content, err := hc.Extractor.ExtractContent(doc)
-				if err != nil {
-					fmt.Printf("Failed to extract content: %s - %s\n", parsedLink.URL.String(), err)
-					return
-				}
-
-				// Save content
-				if err := hc.Storage.SaveNodeContent(parsedLink, content); err != nil {
-					fmt.Printf("Failed to save content: %s - %s\n", parsedLink.URL.String(), err)
-				}
+// isExternalLink reports whether a link points to another host than the root page
+func isExternalLink(rootNode *node.WebNode, link string) bool {
+	linkURL, err := url.Parse(link)
+	if err != nil || linkURL.Host == "" {
+		return false
+	}
+	return !strings.EqualFold(linkURL.Host, rootNode.URL.Host)
+}
+
+// robotsDirectives collects the robots directives of a page from <meta name="robots"> and the X-Robots-Tag header.
+// Each directive maps to the source it came from; "none" counts as both noindex and nofollow.
+func (hc *HarvesterContext) robotsDirectives(doc *html.Node, header http.Header) map[string]string {
+	directives := make(map[string]string)
+	add := func(value string, source string) {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			// X-Robots-Tag values may be scoped to a crawler, e.g. "googlebot: noindex"
+			if i := strings.LastIndex(directive, ":"); i >= 0 {
+				directive = strings.TrimSpace(directive[i+1:])
+			}
+			if directive == "none" {
+				directives["noindex"] = source
+				directives["nofollow"] = source
+			} else if directive != "" {
+				directives[directive] = source
 			}
 		}
-	} else if hc.Debug {
-		// Filtered links, only show in debug mode
-		if hc.WebTree.IsVisited(link) {
-			fmt.Printf("Filtered (duplicated): %s\n", link)
-		} else {
-			fmt.Printf("Filtered (not parent): %s\n", link)
+	}
+
+	add(hc.Extractor.ExtractMetadata(doc)["robots"], "meta")
+	for _, value := range header.Values("X-Robots-Tag") {
+		add(value, "X-Robots-Tag")
+	}
+
+	return directives
+}
+
+// noFollow reports whether links on a page must not be followed because of a robots nofollow directive
+func (hc *HarvesterContext) noFollow(n *node.WebNode) bool {
+	source, ok := n.Metadata["nofollow"]
+	if ok {
+		hc.Logger.Debug("Not following links (nofollow)", "url", n.URLWithoutFragment(), "source", source)
+	}
+	return ok
+}
+
+// nextPageURL returns the rel="next" URL of a page when pagination is followed.
+// It must be called before harvestPage, which strips navigation from the document.
+func (hc *HarvesterContext) nextPageURL(n *node.WebNode, doc *html.Node) string {
+	if !hc.FollowPagination {
+		return ""
+	}
+	return hc.pageTools().ExtractNextPageURL(doc, n.URL)
+}
+
+// followPagination downloads the chain of next pages following a page, up to the depth limit.
+// Next pages bypass the parent-path filter and query-variant filtering, so "?page=2" chains are followed,
+// but must be on the same host.
+func (hc *HarvesterContext) followPagination(n *node.WebNode, nextPage string) {
+	current := n
+	for nextPage != "" && !hc.stopped() && !hc.noFollow(current) && hc.shouldFollow(nextPage) {
+		nextURL, err := url.Parse(nextPage)
+		if err != nil || nextURL.Host != current.URL.Host {
+			hc.Logger.Debug("Filtered (external next page)", "url", nextPage)
+			return
+		}
+		if !hc.WebTree.IsAllowedDepth(current.Depth + 1) {
+			hc.Logger.Debug("Filtered (next page beyond max depth)", "url", nextPage)
+			return
 		}
+
+		nextNode, _ := hc.WebTree.AddURL(nextPage, current)
+		if nextNode == nil {
+			return
+		}
+
+		hc.Logger.Info("Following next page", "url", nextPage)
+		doc, header, err := hc.fetchNode(nextNode)
+		if err != nil {
+			hc.fetchFailed(nil, nextNode, err)
+			return
+		}
+
+		following := hc.nextPageURL(nextNode, doc)
+		if err := hc.harvestPage(nextNode, doc, header); err != nil {
+			hc.reportError(nextPage, err)
+			return
+		}
+
+		current, nextPage = nextNode, following
 	}
 }
 
@@ -382,5 +1481,6 @@ func (hc *HarvesterContext) GetTree() *tree.WebTree {
 
 // FetchDocument gets the document for a specified URL
 func (hc *HarvesterContext) FetchDocument(url string) (*html.Node, error) {
-	return hc.Crawler.FetchPage(url)
+	doc, _, err := hc.fetch(url)
+	return doc, err
 }
@@ -0,0 +1,788 @@
+package harvester
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/qrtt1/doc-harvester/pkg/crawler"
+	"github.com/qrtt1/doc-harvester/pkg/storage"
+	"github.com/qrtt1/doc-harvester/pkg/tree"
+)
+
+// fixtureSite serves a small website from memory and records the paths it was asked for
+type fixtureSite struct {
+	Pages     map[string]string // Path (with query, if any) -> HTML
+	Redirects map[string]string // Path -> redirect target
+
+	mutex     sync.Mutex
+	requested []string
+}
+
+func (s *fixtureSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requested = append(s.requested, r.URL.RequestURI())
+	s.mutex.Unlock()
+
+	if target, ok := s.Redirects[r.URL.Path]; ok {
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+	page, ok := s.Pages[r.URL.RequestURI()]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, page)
+}
+
+// Requested returns the paths requested so far, in order
+func (s *fixtureSite) Requested() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.requested...)
+}
+
+// start serves the site until the test ends
+func (s *fixtureSite) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fixturePage returns an HTML page with a title, a paragraph and links
+func fixturePage(title string, links ...string) string {
+	var b strings.Builder
+	b.WriteString("<html><head><title>" + title + "</title></head><body><main><h1>" + title + "</h1>")
+	b.WriteString("<p>This is the " + title + " page of the fixture site.</p><ul>")
+	for _, link := range links {
+		b.WriteString(`<li><a href="` + link + `">` + link + "</a></li>")
+	}
+	b.WriteString("</ul></main></body></html>")
+	return b.String()
+}
+
+// newXMLTestContext creates a downloading context for a fixture site, writing an XML file in a temporary directory.
+// It returns the context and the path of the XML file.
+func newXMLTestContext(t *testing.T, rootURL string, opts ...Option) (*HarvesterContext, string) {
+	t.Helper()
+	xmlPath := filepath.Join(t.TempDir(), "harvest.xml")
+	opts = append([]Option{
+		WithCrawler(crawler.NewCrawler()),
+		WithXMLStorage(xmlPath),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+
+	hc, err := NewHarvesterContext(rootURL, opts...)
+	if err != nil {
+		t.Fatalf("NewHarvesterContext: %v", err)
+	}
+	hc.DownloadAll = true
+	return hc, xmlPath
+}
+
+// newTestContext creates a downloading context for a fixture site, storing pages in memory
+func newTestContext(t *testing.T, rootURL string, opts ...Option) (*HarvesterContext, *storage.MemoryStorage) {
+	t.Helper()
+	memory := storage.NewMemoryStorage()
+	opts = append([]Option{
+		WithCrawler(crawler.NewCrawler()),
+		WithStorage(memory),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+
+	hc, err := NewHarvesterContext(rootURL, opts...)
+	if err != nil {
+		t.Fatalf("NewHarvesterContext: %v", err)
+	}
+	hc.DownloadAll = true
+	return hc, memory
+}
+
+// download runs a crawl to the end
+func download(t *testing.T, hc *HarvesterContext) {
+	t.Helper()
+	if err := hc.Download(); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	hc.Cleanup()
+}
+
+// readXMLPages returns the pages of an XML harvest file by path, with their links made relative to the server
+func readXMLPages(t *testing.T, server *httptest.Server, xmlPath string) map[string]storage.XMLPage {
+	t.Helper()
+	data, err := os.ReadFile(xmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc storage.XMLDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid XML output: %v\n%s", err, data)
+	}
+
+	pages := make(map[string]storage.XMLPage)
+	for _, page := range doc.Pages {
+		for i, link := range page.Links {
+			page.Links[i].URL = strings.TrimPrefix(link.URL, server.URL)
+		}
+		pages[strings.TrimPrefix(page.URL, server.URL)] = page
+	}
+	return pages
+}
+
+// pageTitles returns the title of each stored page by path
+func pageTitles(server *httptest.Server, pages []storage.XMLPage) map[string]string {
+	titles := make(map[string]string)
+	for _, page := range pages {
+		titles[strings.TrimPrefix(page.URL, server.URL)] = page.Title
+	}
+	return titles
+}
+
+func TestDownloadFixtureSite(t *testing.T) {
+	// The default scope follows the seed's parent directory and any prompt-engineering page of its host
+	site := &fixtureSite{
+		Pages: map[string]string{
+			"/prompt-engineering/docs/":                   fixturePage("Docs", "guide/", "api/reference.html", "missing.html", "old.html"),
+			"/prompt-engineering/docs/guide/":             fixturePage("Guide", "install.html", "../"),
+			"/prompt-engineering/docs/guide/install.html": fixturePage("Install", "../"),
+			"/prompt-engineering/docs/api/reference.html": fixturePage("Reference", "../guide/"),
+			"/prompt-engineering/docs/moved.html":         fixturePage("Moved"),
+		},
+		Redirects: map[string]string{"/prompt-engineering/docs/old.html": "/prompt-engineering/docs/moved.html"},
+	}
+	server := site.start(t)
+
+	hc, xmlPath := newXMLTestContext(t, server.URL+"/prompt-engineering/docs/")
+	download(t, hc)
+
+	// Every page reachable from the seed is stored, with the links found on it
+	type page struct {
+		Title string
+		Links []string
+		Error bool
+	}
+	want := map[string]page{
+		"/prompt-engineering/docs/": {Title: "Docs", Links: []string{
+			"/prompt-engineering/docs/guide/",
+			"/prompt-engineering/docs/api/reference.html",
+			"/prompt-engineering/docs/missing.html",
+			"/prompt-engineering/docs/old.html",
+		}},
+		"/prompt-engineering/docs/guide/": {Title: "Guide", Links: []string{
+			"/prompt-engineering/docs/guide/install.html",
+			"/prompt-engineering/docs/",
+		}},
+		"/prompt-engineering/docs/guide/install.html": {Title: "Install", Links: []string{"/prompt-engineering/docs/"}},
+		"/prompt-engineering/docs/api/reference.html": {Title: "Reference", Links: []string{"/prompt-engineering/docs/guide/"}},
+		"/prompt-engineering/docs/old.html":           {Title: "Moved"}, // Stored under the linked URL, with the content of the redirect target
+		"/prompt-engineering/docs/missing.html":       {Error: true},    // Recorded with its error
+	}
+	got := make(map[string]page)
+	for path, p := range readXMLPages(t, server, xmlPath) {
+		var links []string
+		for _, link := range p.Links {
+			links = append(links, link.URL)
+		}
+		got[path] = page{Title: p.Title, Links: links, Error: p.Error != ""}
+		if p.Error != "" {
+			if !strings.Contains(p.Error, "404") {
+				t.Errorf("error of %s = %q, want a 404", path, p.Error)
+			}
+		} else if !strings.Contains(string(p.Content), "This is the "+p.Title+" page") {
+			t.Errorf("content of %s = %q", path, p.Content)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pages written = %v, want %v", got, want)
+	}
+
+	// Each page is requested once, even when linked from several pages
+	seen := make(map[string]bool)
+	for _, path := range site.Requested() {
+		if seen[path] {
+			t.Errorf("%s was requested more than once", path)
+		}
+		seen[path] = true
+	}
+	if !seen["/prompt-engineering/docs/missing.html"] {
+		t.Errorf("the missing page was not requested: %v", site.Requested())
+	}
+}
+
+func TestMaxPathRepetition(t *testing.T) {
+	// Every page links to a/b/ relative to itself, so the links resolve ever deeper: /a/b/, /a/b/a/b/, ...
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, fixturePage("Page at "+r.URL.Path, "a/b/"))
+	}))
+	t.Cleanup(server.Close)
+
+	for _, tt := range []struct {
+		repetition int
+		want       int
+	}{
+		{0, 20}, // Only stopped by the page limit
+		{1, 2},  // /docs/ and /docs/a/b/
+		{2, 3},  // Also /docs/a/b/a/b/
+	} {
+		t.Run(strconv.Itoa(tt.repetition), func(t *testing.T) {
+			hc, xmlPath := newXMLTestContext(t, server.URL+"/prompt-engineering/docs/", WithMaxDepth(0))
+			hc.MaxPathRepetition = tt.repetition
+			hc.MaxPages = 20
+			download(t, hc)
+
+			if got := len(readXMLPages(t, server, xmlPath)); got != tt.want {
+				t.Errorf("stored %d pages, want %d", got, tt.want)
+			}
+			if refused := hc.Stats.Skipped["repetitive path"] > 0; refused != (tt.repetition > 0) {
+				t.Errorf("skipped = %v, want repetitive paths refused only with a limit", hc.Stats.Skipped)
+			}
+		})
+	}
+}
+
+func TestFollowPaginationQueryChain(t *testing.T) {
+	page := func(title string, next string) string {
+		head := ""
+		if next != "" {
+			head = `<link rel="next" href="` + next + `">`
+		}
+		return "<html><head><title>" + title + "</title>" + head + "</head><body><main><p>Posts of " + title + ".</p>" +
+			`<a href="/blog/list?sort=date">By date</a></main></body></html>`
+	}
+	site := &fixtureSite{Pages: map[string]string{
+		"/blog/list":           page("Page 1", "/blog/list?page=2"),
+		"/blog/list?page=2":    page("Page 2", "/blog/list?page=3"),
+		"/blog/list?page=3":    page("Page 3", ""),
+		"/blog/list?sort=date": page("Sorted", ""),
+	}}
+	server := site.start(t)
+
+	hc, memory := newTestContext(t, server.URL+"/blog/list", WithMaxDepth(5))
+	hc.Scope = ScopeSubtree
+	hc.FollowPagination = true
+	hc.WebTree.SetURLNormalization(tree.URLNormalization{IgnoreQuery: true})
+	download(t, hc)
+
+	// Next pages differ only by their query, but are not filtered as query variants; ?sort=date is
+	want := map[string]string{
+		"/blog/list":        "Page 1",
+		"/blog/list?page=2": "Page 2",
+		"/blog/list?page=3": "Page 3",
+	}
+	if got := pageTitles(server, memory.Pages()); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored pages = %v, want %v", got, want)
+	}
+}
+
+func TestExploreReturnsLinks(t *testing.T) {
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/": fixturePage("Docs", "intro.html", "guide/setup.html#linux", "intro.html", "/blog/", "https://example.org/docs/"),
+	}}
+	server := site.start(t)
+
+	hc, err := NewHarvesterContext(server.URL+"/docs/", WithExploreMode(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc.Scope = ScopeSubtree
+
+	links, err := hc.Explore()
+	if err != nil {
+		t.Fatalf("Explore: %v", err)
+	}
+	want := []string{server.URL + "/docs/intro.html", server.URL + "/docs/guide/setup.html"}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("Explore() = %v, want %v", links, want)
+	}
+
+	// The links are also added to the web tree below the seed
+	var children []string
+	for _, child := range hc.GetTree().RootNode.Children {
+		children = append(children, child.URL.String())
+	}
+	if !reflect.DeepEqual(children, want) {
+		t.Errorf("children of the root = %v, want %v", children, want)
+	}
+}
+
+func TestFragmentLinksToSamePage(t *testing.T) {
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/":           fixturePage("Docs", "#top", "#install", "/docs/#top", "child.html"),
+		"/docs/child.html": fixturePage("Child", "#top", "/docs/child.html#usage"),
+	}}
+	server := site.start(t)
+
+	hc, memory := newTestContext(t, server.URL+"/docs/", WithMaxDepth(3))
+	hc.Scope = ScopeSubtree
+	var discovered []string
+	hc.OnLinkDiscovered = func(link string) bool {
+		discovered = append(discovered, strings.TrimPrefix(link, server.URL))
+		return true
+	}
+	download(t, hc)
+
+	// In-page anchors are dropped before the link hook and never fetched
+	if want := []string{"/docs/child.html"}; !reflect.DeepEqual(discovered, want) {
+		t.Errorf("discovered links = %v, want %v", discovered, want)
+	}
+	if want := []string{"/docs/", "/docs/child.html"}; !reflect.DeepEqual(site.Requested(), want) {
+		t.Errorf("requested %v, want %v", site.Requested(), want)
+	}
+	if len(memory.Pages()) != 2 {
+		t.Errorf("stored %d pages, want 2", len(memory.Pages()))
+	}
+}
+
+func TestRedirectLoopRecorded(t *testing.T) {
+	site := &fixtureSite{
+		Pages:     map[string]string{"/docs/": fixturePage("Docs", "loop.html")},
+		Redirects: map[string]string{"/docs/loop.html": "/docs/loop.html"},
+	}
+	server := site.start(t)
+
+	hc, memory := newTestContext(t, server.URL+"/docs/")
+	hc.Scope = ScopeSubtree
+	hc.RetryFailed = false
+	download(t, hc)
+
+	page, ok := memory.Page(server.URL + "/docs/loop.html")
+	if !ok || !strings.Contains(page.Error, "redirect loop") {
+		t.Errorf("stored loop page error = %q, want a redirect loop", page.Error)
+	}
+	if hc.Stats.Failures["redirect"] != 1 {
+		t.Errorf("failures = %v, want one redirect", hc.Stats.Failures)
+	}
+}
+
+func TestUnlimitedDepthCyclicSite(t *testing.T) {
+	// Each page links to the next and back to the first, so the crawl only ends because pages are visited once
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/":       fixturePage("Start", "a.html"),
+		"/docs/a.html": fixturePage("A", "b.html", "/docs/"),
+		"/docs/b.html": fixturePage("B", "c.html", "a.html"),
+		"/docs/c.html": fixturePage("C", "d.html", "/docs/"),
+		"/docs/d.html": fixturePage("D", "a.html", "b.html", "c.html"),
+	}}
+	server := site.start(t)
+
+	hc, memory := newTestContext(t, server.URL+"/docs/", WithMaxDepth(0))
+	hc.Scope = ScopeSubtree
+	download(t, hc)
+
+	if len(memory.Pages()) != 5 || len(site.Requested()) != 5 {
+		t.Errorf("stored %d pages with %d requests, want 5 of each: %v", len(memory.Pages()), len(site.Requested()), site.Requested())
+	}
+	if depth := hc.GetTree().FindNode(server.URL + "/docs/d.html").Depth; depth != 4 {
+		t.Errorf("depth of d.html = %d, want 4 (beyond the default limit)", depth)
+	}
+}
+
+func TestUnlimitedDepthPageLimit(t *testing.T) {
+	// An endless site: every page links to a new one
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/docs/"))
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, fixturePage("Page "+strconv.Itoa(n), strconv.Itoa(n+1)))
+	}))
+	t.Cleanup(server.Close)
+
+	hc, memory := newTestContext(t, server.URL+"/docs/", WithMaxDepth(0))
+	hc.Scope = ScopeSubtree
+	hc.MaxPages = 25
+	download(t, hc)
+
+	if got := len(memory.Pages()); got != 25 || requests.Load() != 25 {
+		t.Errorf("stored %d pages with %d requests, want 25 of each", got, requests.Load())
+	}
+}
+
+func TestFollowExternalDepth(t *testing.T) {
+	external := &fixtureSite{Pages: map[string]string{
+		"/spec/":             fixturePage("Spec", "details.html"),
+		"/spec/details.html": fixturePage("Details"),
+	}}
+	externalServer := external.start(t)
+
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/":           fixturePage("Docs", "guide.html", externalServer.URL+"/spec/"),
+		"/docs/guide.html": fixturePage("Guide"),
+	}}
+	server := site.start(t)
+
+	for _, tt := range []struct {
+		depth int
+		want  []string
+	}{
+		{0, nil},
+		{1, []string{"/spec/"}}, // Stored, but its links are not followed
+		{2, []string{"/spec/", "/spec/details.html"}},
+	} {
+		t.Run(strconv.Itoa(tt.depth), func(t *testing.T) {
+			external.mutex.Lock()
+			external.requested = nil
+			external.mutex.Unlock()
+
+			hc, memory := newTestContext(t, server.URL+"/docs/", WithMaxDepth(5))
+			hc.Scope = ScopeSubtree
+			hc.FollowExternalDepth = tt.depth
+			download(t, hc)
+
+			if got := external.Requested(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requested from the external host %v, want %v", got, tt.want)
+			}
+			if got := len(memory.Pages()); got != 2+len(tt.want) {
+				t.Errorf("stored %d pages, want %d", got, 2+len(tt.want))
+			}
+		})
+	}
+}
+
+func TestInvalidSeedURL(t *testing.T) {
+	_, err := NewHarvesterContext("http://[::1", WithExploreMode())
+	if err == nil || !strings.Contains(err.Error(), `invalid URL "http://[::1"`) {
+		t.Errorf("NewHarvesterContext error = %v, want an invalid URL error", err)
+	}
+
+	hc, err := NewHarvesterContext("https://example.org/docs/", WithExploreMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hc.AddSeedURL("docs.example.org"); err == nil || !strings.Contains(err.Error(), "missing scheme") {
+		t.Errorf("AddSeedURL error = %v, want a missing scheme error", err)
+	}
+}
+
+func TestBaseURLDifferentFromSeed(t *testing.T) {
+	// A mirror serving the pages of docs.example.org/v2/docs/ under /mirror/docs/, with links written for the canonical site
+	site := &fixtureSite{Pages: map[string]string{
+		"/mirror/docs/":           fixturePage("Docs", "https://docs.example.org/v2/docs/guide.html", "/v2/docs/api.html", "intro.html"),
+		"/mirror/docs/guide.html": fixturePage("Guide"),
+		"/mirror/docs/api.html":   fixturePage("API"),
+		"/mirror/docs/intro.html": fixturePage("Intro"),
+	}}
+	server := site.start(t)
+
+	hc, memory := newTestContext(t, server.URL+"/mirror/docs/", WithBaseURL("https://docs.example.org/v2/docs/"))
+	hc.Scope = ScopeSubtree
+	download(t, hc)
+
+	want := map[string]string{
+		"/mirror/docs/":           "Docs",
+		"/mirror/docs/guide.html": "Guide",
+		"/mirror/docs/api.html":   "API",
+		"/mirror/docs/intro.html": "Intro",
+	}
+	if got := pageTitles(server, memory.Pages()); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored pages = %v, want %v", got, want)
+	}
+}
+
+func TestMaxLinksPerPage(t *testing.T) {
+	// An index page linking to 100 pages, the first twice and once to itself
+	site := &fixtureSite{Pages: map[string]string{}}
+	links := []string{"/docs/", "page0.html"}
+	for i := 0; i < 100; i++ {
+		page := "page" + strconv.Itoa(i) + ".html"
+		links = append(links, page)
+		site.Pages["/docs/"+page] = fixturePage("Page " + strconv.Itoa(i))
+	}
+	site.Pages["/docs/"] = fixturePage("Index", links...)
+	server := site.start(t)
+
+	hc, memory := newTestContext(t, server.URL+"/docs/")
+	hc.Scope = ScopeSubtree
+	hc.MaxLinksPerPage = 10
+	download(t, hc)
+
+	// Only accepted links count: the self link and the duplicate do not use up the budget
+	want := map[string]string{"/docs/": "Index"}
+	for i := 0; i < 10; i++ {
+		want["/docs/page"+strconv.Itoa(i)+".html"] = "Page " + strconv.Itoa(i)
+	}
+	if got := pageTitles(server, memory.Pages()); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored pages = %v, want %v", got, want)
+	}
+	if got := len(site.Requested()); got != 11 {
+		t.Errorf("made %d requests, want 11: %v", got, site.Requested())
+	}
+
+	explorer, err := NewHarvesterContext(server.URL+"/docs/", WithExploreMode(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	explorer.Scope = ScopeSubtree
+	explorer.MaxLinksPerPage = 10
+	discovered, err := explorer.Explore()
+	if err != nil {
+		t.Fatalf("Explore: %v", err)
+	}
+	if len(discovered) != 10 || discovered[9] != server.URL+"/docs/page9.html" {
+		t.Errorf("Explore() = %v, want the first 10 pages", discovered)
+	}
+}
+
+func TestOutputToWriter(t *testing.T) {
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/":           fixturePage("Docs", "guide.html"),
+		"/docs/guide.html": fixturePage("Guide"),
+	}}
+	server := site.start(t)
+
+	// What -output - sets up for each format: the result is written to the writer, not to a file
+	outputPath := filepath.Join(t.TempDir(), "output")
+	tests := []struct {
+		name    string
+		storage func(t *testing.T, out *bytes.Buffer) Storage
+		check   func(t *testing.T, output string)
+	}{
+		{
+			name: "xml",
+			storage: func(t *testing.T, out *bytes.Buffer) Storage {
+				s, err := storage.NewXMLStorage(outputPath, server.URL+"/docs/")
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Output = out
+				return s
+			},
+			check: func(t *testing.T, output string) {
+				var doc storage.XMLDocument
+				if err := xml.Unmarshal([]byte(output), &doc); err != nil {
+					t.Fatalf("output is not an XML document: %v\n%s", err, output)
+				}
+				if got := pageTitles(server, doc.Pages); !reflect.DeepEqual(got, map[string]string{"/docs/": "Docs", "/docs/guide.html": "Guide"}) {
+					t.Errorf("pages written = %v", got)
+				}
+			},
+		},
+		{
+			name: "markdown",
+			storage: func(t *testing.T, out *bytes.Buffer) Storage {
+				s, err := storage.NewSingleMarkdownStorage(outputPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Output = out
+				return s
+			},
+			check: func(t *testing.T, output string) {
+				for _, want := range []string{"# Docs\n", "# Guide\n", "Source: <" + server.URL + "/docs/guide.html>"} {
+					if !strings.Contains(output, want) {
+						t.Errorf("output lacks %q:\n%s", want, output)
+					}
+				}
+			},
+		},
+		{
+			name: "jsonl",
+			storage: func(t *testing.T, out *bytes.Buffer) Storage {
+				return storage.NewJSONLWriterStorage(out)
+			},
+			check: func(t *testing.T, output string) {
+				var titles []string
+				for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+					var record storage.JSONLRecord
+					if err := json.Unmarshal([]byte(line), &record); err != nil {
+						t.Fatalf("line is not a JSON record: %v\n%s", err, line)
+					}
+					titles = append(titles, record.Title)
+				}
+				if !reflect.DeepEqual(titles, []string{"Docs", "Guide"}) {
+					t.Errorf("records written for %v, want Docs and Guide", titles)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			hc, _ := newTestContext(t, server.URL+"/docs/", WithStorage(tt.storage(t, &out)))
+			hc.Scope = ScopeSubtree
+			download(t, hc)
+
+			tt.check(t, out.String())
+			if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+				t.Errorf("output file was written: %v", err)
+			}
+		})
+	}
+}
+
+func TestCrawlOrder(t *testing.T) {
+	// Two sections of two pages each, the first page linking to a deeper page
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/":               fixturePage("Docs", "a/", "b/"),
+		"/docs/a/":             fixturePage("A", "a1.html", "a2.html"),
+		"/docs/a/a1.html":      fixturePage("A1", "a1/deep.html"),
+		"/docs/a/a2.html":      fixturePage("A2"),
+		"/docs/a/a1/deep.html": fixturePage("Deep"),
+		"/docs/b/":             fixturePage("B", "b1.html", "b2.html"),
+		"/docs/b/b1.html":      fixturePage("B1"),
+		"/docs/b/b2.html":      fixturePage("B2"),
+	}}
+	server := site.start(t)
+
+	breadthFirst := []string{"/docs/", "/docs/a/", "/docs/b/", "/docs/a/a1.html", "/docs/a/a2.html", "/docs/b/b1.html", "/docs/b/b2.html", "/docs/a/a1/deep.html"}
+	tests := []struct {
+		name  string
+		order string
+		want  []string
+	}{
+		{"default", "", breadthFirst},
+		{"bfs", CrawlOrderBFS, breadthFirst},
+		{"dfs", CrawlOrderDFS, []string{"/docs/", "/docs/a/", "/docs/a/a1.html", "/docs/a/a1/deep.html", "/docs/a/a2.html", "/docs/b/", "/docs/b/b1.html", "/docs/b/b2.html"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site.mutex.Lock()
+			site.requested = nil
+			site.mutex.Unlock()
+
+			hc, _ := newTestContext(t, server.URL+"/docs/", WithMaxDepth(5))
+			hc.Scope = ScopeSubtree
+			hc.CrawlOrder = tt.order
+			download(t, hc)
+
+			if got := site.Requested(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requested %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSubtreeURL(t *testing.T) {
+	tests := []struct {
+		root, link string
+		want       bool
+	}{
+		{"https://example.org/docs/guide/", "https://example.org/docs/guide/", true},
+		{"https://example.org/docs/guide/", "https://example.org/docs/guide", true},
+		{"https://example.org/docs/guide/", "https://example.org/docs/guide/install.html", true},
+		{"https://example.org/docs/guide/", "https://example.org/docs/guide/advanced/tuning.html", true},
+		{"https://example.org/docs/guide", "https://example.org/docs/guide/install.html", true}, // No extension: a directory
+		{"https://example.org/docs/guide/index.html", "https://example.org/docs/guide/install.html", true},
+		{"https://example.org/docs/guide/", "https://example.org/docs/", false},
+		{"https://example.org/docs/guide/", "https://example.org/docs/index.html", false},
+		{"https://example.org/docs/guide/", "https://example.org/docs/api/", false},
+		{"https://example.org/docs/guide/", "https://example.org/docs/guidelines.html", false},
+		{"https://example.org/docs/guide/", "https://docs.example.org/docs/guide/install.html", false},
+	}
+	for _, tt := range tests {
+		if got := isSubtreeURL(tt.root, tt.link); got != tt.want {
+			t.Errorf("isSubtreeURL(%q, %q) = %v, want %v", tt.root, tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestScopeSubtree(t *testing.T) {
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/":                           fixturePage("Docs", "guide/"),
+		"/docs/api/":                       fixturePage("API"),
+		"/docs/guide/":                     fixturePage("Guide", "../", "../api/", "/docs/guidelines.html", "install.html", "advanced/"),
+		"/docs/guidelines.html":            fixturePage("Guidelines"),
+		"/docs/guide/install.html":         fixturePage("Install", "../../"),
+		"/docs/guide/advanced/":            fixturePage("Advanced", "tuning.html", "../../api/"),
+		"/docs/guide/advanced/tuning.html": fixturePage("Tuning"),
+	}}
+	server := site.start(t)
+
+	hc, memory := newTestContext(t, server.URL+"/docs/guide/", WithMaxDepth(5))
+	hc.Scope = ScopeSubtree
+	download(t, hc)
+
+	// The parent, the sibling and a page sharing the directory's prefix are left out
+	want := map[string]string{
+		"/docs/guide/":                     "Guide",
+		"/docs/guide/install.html":         "Install",
+		"/docs/guide/advanced/":            "Advanced",
+		"/docs/guide/advanced/tuning.html": "Tuning",
+	}
+	if got := pageTitles(server, memory.Pages()); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored pages = %v, want %v", got, want)
+	}
+	if got := len(site.Requested()); got != len(want) {
+		t.Errorf("requested %v, want only the pages of the subtree", site.Requested())
+	}
+	if hc.Stats.Skipped["out of scope"] == 0 {
+		t.Errorf("skipped = %v, want links out of scope", hc.Stats.Skipped)
+	}
+}
+
+func TestMaxOutputSize(t *testing.T) {
+	site := &fixtureSite{Pages: map[string]string{
+		"/docs/":       fixturePage("Docs", "a.html", "b.html", "c.html", "d.html"),
+		"/docs/a.html": fixturePage("A"),
+		"/docs/b.html": fixturePage("B"),
+		"/docs/c.html": fixturePage("C"),
+		"/docs/d.html": fixturePage("D"),
+	}}
+	server := site.start(t)
+
+	// The size of each page's content, in crawl order
+	hc, memory := newTestContext(t, server.URL+"/docs/")
+	hc.Scope = ScopeSubtree
+	download(t, hc)
+	var sizes []int64
+	for _, page := range memory.Pages() {
+		sizes = append(sizes, int64(len(page.Content)))
+	}
+	if len(sizes) != 5 {
+		t.Fatalf("stored %d pages without a budget, want 5", len(sizes))
+	}
+
+	tests := []struct {
+		name   string
+		budget int64
+		saved  int
+	}{
+		{"smaller than the first page", sizes[0] - 1, 0},
+		{"exactly two pages", sizes[0] + sizes[1], 2},
+		{"one byte short of three pages", sizes[0] + sizes[1] + sizes[2] - 1, 2},
+		{"all pages", sizes[0] + sizes[1] + sizes[2] + sizes[3] + sizes[4], 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, memory := newTestContext(t, server.URL+"/docs/")
+			hc.Scope = ScopeSubtree
+			hc.MaxOutputSize = tt.budget
+			download(t, hc)
+
+			var total int64
+			for _, page := range memory.Pages() {
+				total += int64(len(page.Content))
+			}
+			if got := len(memory.Pages()); got != tt.saved || total > tt.budget {
+				t.Errorf("saved %d pages of %d bytes with a budget of %d, want %d pages", got, total, tt.budget, tt.saved)
+			}
+
+			// The page that would outgrow the budget stops the crawl
+			skipped := 0
+			if tt.saved < 5 {
+				skipped = 1
+			}
+			if hc.Stats.Skipped["output size"] != skipped || int(hc.Stats.PagesSaved) != tt.saved {
+				t.Errorf("skipped %v and saved %d, want %d skipped for the output size", hc.Stats.Skipped, hc.Stats.PagesSaved, skipped)
+			}
+		})
+	}
+}
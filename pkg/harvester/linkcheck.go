@@ -0,0 +1,131 @@
+package harvester
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// HeadChecker is implemented by page fetchers that can check a URL without downloading it
+type HeadChecker interface {
+	// Head returns the status code of a URL
+	Head(urlStr string) (int, error)
+}
+
+// LinkReport is the result of a link check
+type LinkReport struct {
+	Checked int            `json:"checked"` // Distinct links checked
+	Counts  map[string]int `json:"counts"`  // Checked links by status class: 2xx, 3xx, 4xx, 5xx or error
+	Broken  []BrokenLink   `json:"broken"`  // Links answering 4xx or 5xx, or failing altogether
+}
+
+// BrokenLink is a link that failed the link check
+type BrokenLink struct {
+	URL     string `json:"url"`
+	Status  int    `json:"status,omitempty"` // Status code; 0 if the request failed
+	Error   string `json:"error,omitempty"`
+	FoundOn string `json:"foundOn"` // First page the link was found on
+}
+
+// String formats the report as a multi-line summary followed by the broken links
+func (r *LinkReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Links checked: %d\n", r.Checked)
+	fmt.Fprintf(&sb, "By status:     %s\n", formatCounts(r.Counts))
+	for _, link := range r.Broken {
+		reason := link.Error
+		if link.Status != 0 {
+			reason = fmt.Sprint(link.Status)
+		}
+		fmt.Fprintf(&sb, "Broken: %s (%s) on %s\n", link.URL, reason, link.FoundOn)
+	}
+	return sb.String()
+}
+
+// CheckLinks is like CheckLinksContext without a deadline
+func (hc *HarvesterContext) CheckLinks() (*LinkReport, error) {
+	return hc.CheckLinksContext(context.Background())
+}
+
+// CheckLinksContext crawls the site like a download, but instead of storing pages, checks every link found on them.
+// Links are checked once each with a HEAD request; nothing is extracted or stored.
+func (hc *HarvesterContext) CheckLinksContext(ctx context.Context) (*LinkReport, error) {
+	headChecker, ok := hc.Crawler.(HeadChecker)
+	if !ok {
+		return nil, fmt.Errorf("page fetcher can't check links")
+	}
+
+	hc.ctx = ctx
+	defer func() { hc.ctx = nil }()
+	hc.DownloadAll = true
+
+	report := &LinkReport{Counts: make(map[string]int)}
+	checked := make(map[string]bool)
+	for _, rootNode := range hc.WebTree.Roots {
+		queue := []*node.WebNode{rootNode}
+		for len(queue) > 0 && !hc.stopped() {
+			n := queue[0]
+			queue = queue[1:]
+
+			doc, _, err := hc.fetchNode(n)
+			if err != nil {
+				if n == rootNode {
+					return nil, fmt.Errorf("failed to fetch the URL: %w", err)
+				}
+				continue
+			}
+
+			// Check every link of the page, including the ones that are not followed
+			links, err := hc.Crawler.ExtractLinks(doc, n.URL.String())
+			if err != nil {
+				hc.reportError(n.URL.String(), fmt.Errorf("failed to extract links: %w", err))
+				continue
+			}
+			hc.Stats.LinksDiscovered += len(links)
+			for _, link := range links {
+				link = hc.removeFragment(link)
+				if checked[link] || hc.stopped() {
+					continue
+				}
+				checked[link] = true
+				hc.checkLink(headChecker, report, link, n.URLWithoutFragment())
+			}
+
+			if !hc.SinglePage {
+				queue = append(queue, hc.discoverLinks(rootNode, n, doc)...)
+			}
+		}
+	}
+
+	sort.Slice(report.Broken, func(i, j int) bool { return report.Broken[i].URL < report.Broken[j].URL })
+
+	if hc.stopped() {
+		hc.Logger.Warn("Link check stopped before completion", "reason", hc.stopReason())
+	}
+
+	return report, nil
+}
+
+// checkLink checks a single link and records the result in the report
+func (hc *HarvesterContext) checkLink(headChecker HeadChecker, report *LinkReport, link string, foundOn string) {
+	report.Checked++
+
+	status, err := headChecker.Head(link)
+	if err != nil {
+		report.Counts["error"]++
+		report.Broken = append(report.Broken, BrokenLink{URL: link, Error: err.Error(), FoundOn: foundOn})
+		hc.Logger.Warn("Broken link", "url", link, "foundOn", foundOn, "error", err)
+		return
+	}
+
+	report.Counts[fmt.Sprintf("%dxx", status/100)]++
+	if status >= 400 {
+		report.Broken = append(report.Broken, BrokenLink{URL: link, Status: status, FoundOn: foundOn})
+		hc.Logger.Warn("Broken link", "url", link, "foundOn", foundOn, "status", status)
+		return
+	}
+	hc.Logger.Debug("Checked link", "url", link, "status", status)
+}
@@ -0,0 +1,219 @@
+package harvester
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/qrtt1/doc-harvester/pkg/crawler"
+	"github.com/qrtt1/doc-harvester/pkg/extractor"
+	"github.com/qrtt1/doc-harvester/pkg/storage"
+	"github.com/qrtt1/doc-harvester/pkg/tree"
+)
+
+// Option configures a HarvesterContext created by NewHarvesterContext
+type Option func(hc *HarvesterContext) error
+
+// WithBaseURL sets the base URL (defaults to the root URL)
+func WithBaseURL(baseURL string) Option {
+	return func(hc *HarvesterContext) error {
+		hc.BaseURL = baseURL
+		return nil
+	}
+}
+
+// WithMaxDepth sets the maximum crawling depth
+func WithMaxDepth(maxDepth int) Option {
+	return func(hc *HarvesterContext) error {
+		hc.MaxDepth = maxDepth
+		return nil
+	}
+}
+
+// WithDebug enables debug mode; without WithLogger, debug messages are logged to stderr
+func WithDebug(debug bool) Option {
+	return func(hc *HarvesterContext) error {
+		hc.Debug = debug
+		return nil
+	}
+}
+
+// WithLogger sets the logger for progress and diagnostic messages
+func WithLogger(logger *slog.Logger) Option {
+	return func(hc *HarvesterContext) error {
+		hc.Logger = logger
+		return nil
+	}
+}
+
+// WithCrawler replaces the default crawler, e.g. with a fake serving fixture pages
+func WithCrawler(c PageFetcher) Option {
+	return func(hc *HarvesterContext) error {
+		hc.Crawler = c
+		return nil
+	}
+}
+
+// WithExtractor replaces the default content extractor, e.g. with one for a site with peculiar markup
+func WithExtractor(e Extractor) Option {
+	return func(hc *HarvesterContext) error {
+		hc.Extractor = e
+		return nil
+	}
+}
+
+// WithInsecureTLS makes the default crawler accept any server certificate, e.g. of an internal server with a self-signed one.
+// This disables protection against impersonation; trusting the server's CA with WithCACert is the better option.
+func WithInsecureTLS() Option {
+	return func(hc *HarvesterContext) error {
+		c, err := hc.defaultCrawler()
+		if err != nil {
+			return fmt.Errorf("insecure TLS: %w", err)
+		}
+		c.InsecureSkipVerify = true
+		c.ResetTransport()
+		return nil
+	}
+}
+
+// WithCACert makes the default crawler trust the certificate authorities of a PEM file, besides the system's
+func WithCACert(path string) Option {
+	return func(hc *HarvesterContext) error {
+		c, err := hc.defaultCrawler()
+		if err != nil {
+			return err
+		}
+		if c.RootCAs, err = crawler.LoadCACert(path); err != nil {
+			return err
+		}
+		c.ResetTransport()
+		return nil
+	}
+}
+
+// defaultCrawler returns the context's crawler for options configuring it, creating it if no crawler is set yet
+func (hc *HarvesterContext) defaultCrawler() (*crawler.Crawler, error) {
+	if hc.Crawler == nil {
+		hc.Crawler = crawler.NewCrawler()
+	}
+	c, ok := hc.Crawler.(*crawler.Crawler)
+	if !ok {
+		return nil, fmt.Errorf("the page fetcher is not a *crawler.Crawler")
+	}
+	return c, nil
+}
+
+// WithStorage sets where downloaded content is stored
+func WithStorage(s Storage) Option {
+	return func(hc *HarvesterContext) error {
+		hc.Storage = s
+		return nil
+	}
+}
+
+// WithXMLStorage stores downloaded content in a single XML file
+func WithXMLStorage(xmlFilePath string) Option {
+	return func(hc *HarvesterContext) error {
+		s, err := storage.NewXMLStorage(xmlFilePath, hc.RootURL)
+		if err != nil {
+			return fmt.Errorf("failed to create XML storage: %w", err)
+		}
+		hc.Storage = s
+		return nil
+	}
+}
+
+// WithSingleMarkdownStorage stores downloaded content in one consolidated Markdown file, written by Cleanup
+func WithSingleMarkdownStorage(mdFilePath string, separator string) Option {
+	return func(hc *HarvesterContext) error {
+		s, err := storage.NewSingleMarkdownStorage(mdFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to create Markdown storage: %w", err)
+		}
+		if separator != "" {
+			s.Separator = separator
+		}
+		hc.Storage = s
+		return nil
+	}
+}
+
+// WithJSONLStorage writes each downloaded page to a JSON Lines file as soon as it is saved
+func WithJSONLStorage(jsonlFilePath string) Option {
+	return func(hc *HarvesterContext) error {
+		s, err := storage.NewJSONLStorage(jsonlFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to create JSON Lines storage: %w", err)
+		}
+		hc.Storage = s
+		return nil
+	}
+}
+
+// WithExploreMode only explores the website structure; nothing is stored
+func WithExploreMode() Option {
+	return func(hc *HarvesterContext) error {
+		hc.Storage = &NullStorage{}
+		hc.DownloadAll = false
+		return nil
+	}
+}
+
+// NewHarvesterContext creates a context for the given root URL, configured by options.
+// Without a storage option, the context explores only (content is not stored).
+func NewHarvesterContext(rootURL string, opts ...Option) (*HarvesterContext, error) {
+	hc := &HarvesterContext{
+		RootURL:     rootURL,
+		BaseURL:     rootURL,
+		PrintedURLs: NewURLSet(),
+		Stats:       CrawlStats{Skipped: make(map[string]int), Failures: make(map[string]int)},
+	}
+
+	for _, opt := range opts {
+		if err := opt(hc); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create web tree
+	webTree, err := tree.NewWebTree(rootURL, hc.MaxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create web tree: %w", err)
+	}
+	hc.WebTree = webTree
+
+	// Fill in default components
+	if hc.Logger == nil {
+		hc.Logger = newLogger(hc.Debug)
+	}
+	if hc.Crawler == nil {
+		hc.Crawler = crawler.NewCrawler()
+	}
+	if hc.Extractor == nil {
+		hc.Extractor = extractor.NewContentExtractor()
+	}
+	if hc.Storage == nil {
+		hc.Storage = &NullStorage{}
+	}
+
+	if c, ok := hc.Crawler.(*crawler.Crawler); ok && c.InsecureSkipVerify {
+		hc.Logger.Warn("TLS certificate verification is disabled")
+	}
+
+	// XML storage reports auto-save errors through the context's logger
+	if xmlStorage, ok := hc.Storage.(*storage.XMLStorage); ok {
+		xmlStorage.Logger = hc.Logger
+	}
+
+	return hc, nil
+}
+
+// newLogger returns the default logger for a context.
+// Debug mode logs debug messages to stderr, unless the default logger already handles them.
+func newLogger(debug bool) *slog.Logger {
+	if debug && !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return slog.Default()
+}
@@ -0,0 +1,104 @@
+package harvester
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qrtt1/doc-harvester/pkg/crawler"
+)
+
+// CrawlStats counts what happened during a crawl
+type CrawlStats struct {
+	PagesFetched    int            `json:"pagesFetched"`    // Pages fetched successfully
+	PagesSaved      int            `json:"pagesSaved"`      // Pages passed to the storage
+	LinksDiscovered int            `json:"linksDiscovered"` // Links extracted from fetched pages
+	Skipped         map[string]int `json:"skipped"`         // Links and pages not processed, by reason
+	Failures        map[string]int `json:"failures"`        // Failed fetches, by HTTP status code or kind of error
+	Retried         int            `json:"retried"`         // Failed pages fetched again by the retry pass
+	Recovered       int            `json:"recovered"`       // Retried pages that were fetched successfully
+	CacheHits       int            `json:"cacheHits"`       // Pages served from the page cache instead of the network
+	Bytes           int64          `json:"bytes"`           // Response bytes read from the network
+	FetchSeconds    float64        `json:"fetchSeconds"`    // Time spent fetching pages, summed over parallel fetches
+	ExtractSeconds  float64        `json:"extractSeconds"`  // Time spent extracting the content of pages
+	SlowPages       int            `json:"slowPages"`       // Pages whose fetch and extraction exceeded the slow threshold
+	StartedAt       time.Time      `json:"startedAt"`
+	ElapsedSeconds  float64        `json:"elapsedSeconds"`
+}
+
+// skip counts a link or page that was not processed
+func (s *CrawlStats) skip(reason string) {
+	if s.Skipped == nil {
+		s.Skipped = make(map[string]int)
+	}
+	s.Skipped[reason]++
+}
+
+// fail counts a failed fetch, keyed by status code where there is one
+func (s *CrawlStats) fail(err error) {
+	if s.Failures == nil {
+		s.Failures = make(map[string]int)
+	}
+
+	var statusErr *crawler.StatusError
+	var redirectErr *crawler.RedirectError
+	switch {
+	case errors.As(err, &statusErr):
+		s.Failures[strconv.Itoa(statusErr.StatusCode)]++
+	case errors.As(err, &redirectErr):
+		s.Failures["redirect"]++
+	default:
+		s.Failures["error"]++
+	}
+}
+
+// String formats the statistics as a multi-line report
+func (s *CrawlStats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Pages fetched:    %d\n", s.PagesFetched)
+	fmt.Fprintf(&sb, "Pages saved:      %d\n", s.PagesSaved)
+	fmt.Fprintf(&sb, "Links discovered: %d\n", s.LinksDiscovered)
+	fmt.Fprintf(&sb, "Skipped:          %s\n", formatCounts(s.Skipped))
+	fmt.Fprintf(&sb, "Failures:         %s\n", formatCounts(s.Failures))
+	if s.Retried > 0 {
+		fmt.Fprintf(&sb, "Retried:          %d (%d recovered)\n", s.Retried, s.Recovered)
+	}
+	if s.CacheHits > 0 {
+		fmt.Fprintf(&sb, "From cache:       %d\n", s.CacheHits)
+	}
+	fmt.Fprintf(&sb, "Bytes downloaded: %d\n", s.Bytes)
+	fmt.Fprintf(&sb, "Fetch time:       %s (extraction %s)\n", seconds(s.FetchSeconds), seconds(s.ExtractSeconds))
+	if s.SlowPages > 0 {
+		fmt.Fprintf(&sb, "Slow pages:       %d\n", s.SlowPages)
+	}
+	fmt.Fprintf(&sb, "Elapsed:          %s\n", seconds(s.ElapsedSeconds))
+	return sb.String()
+}
+
+// seconds formats a number of seconds as a duration rounded to milliseconds
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
+
+// formatCounts formats counters as "total (key: n, ...)" with keys in sorted order
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	total := 0
+	for key, n := range counts {
+		keys = append(keys, key)
+		total += n
+	}
+	if total == 0 {
+		return "0"
+	}
+
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %d", key, counts[key]))
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(parts, ", "))
+}
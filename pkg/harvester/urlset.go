@@ -0,0 +1,46 @@
+package harvester
+
+import "sync"
+
+// URLSet is a set of URLs that is safe for concurrent use; the zero value is an empty set
+type URLSet struct {
+	mutex sync.Mutex
+	urls  map[string]bool
+}
+
+// NewURLSet creates an empty set
+func NewURLSet() *URLSet {
+	return &URLSet{urls: make(map[string]bool)}
+}
+
+// Add adds a URL, reporting whether it was not in the set before.
+// Checking and adding is one step, so of concurrent callers adding the same URL only one gets true.
+func (s *URLSet) Add(u string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.urls[u] {
+		return false
+	}
+	if s.urls == nil {
+		s.urls = make(map[string]bool)
+	}
+	s.urls[u] = true
+	return true
+}
+
+// Contains reports whether a URL is in the set
+func (s *URLSet) Contains(u string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.urls[u]
+}
+
+// Len returns the number of URLs in the set
+func (s *URLSet) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.urls)
+}
@@ -0,0 +1,44 @@
+package harvester
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestURLSetConcurrentAdd(t *testing.T) {
+	const goroutines, urls = 16, 500
+	for name, set := range map[string]*URLSet{"new": NewURLSet(), "zero value": {}} {
+		t.Run(name, func(t *testing.T) {
+			// Every goroutine adds the same URLs, so each must be reported new exactly once
+			var added atomic.Int32
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < urls; i++ {
+						u := "https://example.org/docs/" + strconv.Itoa(i)
+						if set.Add(u) {
+							added.Add(1)
+						}
+						if !set.Contains(u) {
+							t.Errorf("Contains(%q) = false after Add", u)
+						}
+						set.Contains("https://example.org/other/" + strconv.Itoa(i))
+						set.Len()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if added.Load() != urls || set.Len() != urls {
+				t.Errorf("Add reported %d new URLs and the set holds %d, want %d", added.Load(), set.Len(), urls)
+			}
+			if set.Contains("https://example.org/other/0") {
+				t.Error("Contains reports a URL that was never added")
+			}
+		})
+	}
+}
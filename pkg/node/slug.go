@@ -0,0 +1,79 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// slugHashLength is the number of hex digits of the hash added to slugs
+const slugHashLength = 8
+
+// Slug returns a deterministic, filesystem-safe name for the page, see URLSlug
+func (n *WebNode) Slug() string {
+	return URLSlug(n.URL)
+}
+
+// URLSlug returns a deterministic, filesystem-safe relative path for a URL, made of the host and the path,
+// e.g. docs.example.com/guide/intro. The site root becomes host/index, so a path of just /index gets a hash.
+// URLs with a query string, or whose host or path contain characters other than letters, digits, '.', '_' and '-',
+// get a hash of the original path and query appended, so they don't collide with similar URLs.
+func URLSlug(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	// Host names can't contain '_', so it stands in for the colon before a port without risking collisions
+	host, hostChanged := slugSegment(strings.ReplaceAll(strings.ToLower(u.Host), ":", "_"))
+	if host == "" {
+		host = "local"
+	}
+
+	lossy := hostChanged || u.RawQuery != ""
+	parts := []string{host}
+	for _, segment := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		cleaned, changed := slugSegment(segment)
+		lossy = lossy || changed
+		parts = append(parts, cleaned)
+	}
+	if len(parts) == 1 {
+		parts = append(parts, "index")
+	} else if len(parts) == 2 && parts[1] == "index" {
+		// "/index" would collide with the site root
+		lossy = true
+	}
+
+	slug := strings.Join(parts, "/")
+	if lossy {
+		sum := sha256.Sum256([]byte(u.EscapedPath() + "?" + u.RawQuery))
+		slug += "-" + hex.EncodeToString(sum[:])[:slugHashLength]
+	}
+	return slug
+}
+
+// slugSegment replaces the characters of a path segment that are unsafe in file names with '-'
+// and reports whether anything was replaced
+func slugSegment(segment string) (string, bool) {
+	var sb strings.Builder
+	changed := false
+	for _, r := range segment {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			sb.WriteRune(r)
+		case r == '.' && sb.Len() > 0:
+			// A leading dot would hide the file or, as "..", leave the output directory
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('-')
+			changed = true
+		}
+	}
+	return sb.String(), changed
+}
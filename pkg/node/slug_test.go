@@ -0,0 +1,60 @@
+package node
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestURLSlug(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://docs.example.com/guide/intro", "docs.example.com/guide/intro"},
+		{"https://docs.example.com/guide/intro/", "docs.example.com/guide/intro"},
+		{"https://docs.example.com/", "docs.example.com/index"},
+		{"https://docs.example.com", "docs.example.com/index"},
+		{"https://Docs.Example.com/api/v1.2", "docs.example.com/api/v1.2"},
+		{"http://localhost:8080/docs", "localhost_8080/docs"},
+	}
+	for _, tt := range tests {
+		if got := slug(t, tt.url); got != tt.want {
+			t.Errorf("URLSlug(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestURLSlugCollisions(t *testing.T) {
+	// Each group holds URLs of different pages, whose slugs must differ
+	groups := [][]string{
+		{"https://example.org/search", "https://example.org/search?q=a", "https://example.org/search?q=b"},
+		{"https://example.org/a b", "https://example.org/a-b", "https://example.org/a%2Fb", "https://example.org/a/b"},
+		{"https://example.org/", "https://example.org/index"},
+		{"https://example.org/.hidden", "https://example.org/-hidden"},
+		{"https://a.example.org/x", "https://b.example.org/x", "https://example.org:8080/x", "https://example.org/x"},
+	}
+	for _, group := range groups {
+		seen := make(map[string]string)
+		for _, u := range group {
+			s := slug(t, u)
+			if other, ok := seen[s]; ok {
+				t.Errorf("%s and %s have the same slug %q", other, u, s)
+			}
+			seen[s] = u
+			if strings.Contains(s, "..") || strings.ContainsAny(s, " ?%:") {
+				t.Errorf("slug of %s is not filesystem-safe: %q", u, s)
+			}
+		}
+	}
+}
+
+// slug returns the slug of a URL
+func slug(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return URLSlug(u)
+}
@@ -1,7 +1,10 @@
 package node
 
 import (
+	"fmt"
 	"net/url"
+	"strings"
+	"unicode"
 )
 
 // WebNode represents a single node in the website structure
@@ -13,11 +16,33 @@ type WebNode struct {
 	Parent      *WebNode          // Reference to parent node
 	Depth       int               // Depth level in the tree
 	Metadata    map[string]string // Additional information (like size, last modified time)
+	Links       []Link            // Links found on the page, set once the page has been fetched
+	Downloads   []Download        // Links to downloadable files such as PDFs, set once the page has been fetched
+	Anchors     []Anchor          // Heading anchors of the stored content, for deep links
+}
+
+// Link is a link found on a page, with its visible anchor text
+type Link struct {
+	URL  string
+	Text string
+}
+
+// Anchor is a link target in a page, such as a heading's id, with the heading's text
+type Anchor struct {
+	ID   string
+	Text string
+}
+
+// Download is a link to a downloadable file found on a page
+type Download struct {
+	URL  string
+	Text string
+	Path string // Where the file was stored, if it was downloaded
 }
 
 // NewWebNode creates a new WebNode instance
 func NewWebNode(urlStr string, parent *WebNode) (*WebNode, error) {
-	parsedURL, err := url.Parse(urlStr)
+	parsedURL, err := ParseURL(urlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +62,33 @@ func NewWebNode(urlStr string, parent *WebNode) (*WebNode, error) {
 	}, nil
 }
 
+// ParseURL parses an absolute web page URL.
+// Unlike url.Parse, it rejects URLs without an http(s) scheme or host, and URLs containing whitespace.
+func ParseURL(urlStr string) (*url.URL, error) {
+	if i := strings.IndexFunc(urlStr, unicode.IsSpace); i >= 0 {
+		return nil, fmt.Errorf("invalid URL %q: contains whitespace", urlStr)
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid URL %q: %v", urlStr, err)
+	}
+
+	switch {
+	case parsedURL.Scheme == "":
+		return nil, fmt.Errorf("invalid URL %q: missing scheme (e.g. https://)", urlStr)
+	case parsedURL.Scheme != "http" && parsedURL.Scheme != "https":
+		return nil, fmt.Errorf("invalid URL %q: unsupported scheme %q", urlStr, parsedURL.Scheme)
+	case parsedURL.Hostname() == "":
+		return nil, fmt.Errorf("invalid URL %q: missing host", urlStr)
+	}
+
+	return parsedURL, nil
+}
+
 // AddChild adds a child node
 func (n *WebNode) AddChild(child *WebNode) {
 	if child != nil {
@@ -0,0 +1,65 @@
+package node
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIsAnchorOfSamePage(t *testing.T) {
+	page, err := NewWebNode("https://example.org/docs/guide", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://example.org/docs/guide#top", true},
+		{"https://example.org/docs/guide#install", true},
+		{"https://example.org/docs/guide", false}, // Not an anchor
+		{"https://example.org/docs/other#top", false},
+		{"https://example.org/docs/guide?page=2#top", false},
+		{"https://other.example.org/docs/guide#top", false},
+	}
+	for _, tt := range tests {
+		linkURL, err := url.Parse(tt.link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := page.IsAnchorOfSamePage(linkURL); got != tt.want {
+			t.Errorf("IsAnchorOfSamePage(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestParseURLErrors(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string // Part of the error message, empty for a valid URL
+	}{
+		{"https://example.org/docs/", ""},
+		{"http://[::1", "missing ']' in host"},
+		{"example.org/docs", "missing scheme"},
+		{"ftp://example.org/file", `unsupported scheme "ftp"`},
+		{"https:///docs", "missing host"},
+		{"https://example.org/my docs", "contains whitespace"},
+	}
+	for _, tt := range tests {
+		_, err := ParseURL(tt.url)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("ParseURL(%q) error = %v", tt.url, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), tt.url)):
+			t.Errorf("ParseURL(%q) error = %v, want one naming the URL and %q", tt.url, err, tt.want)
+		}
+	}
+}
+
+func TestNewWebNodeInvalidSeed(t *testing.T) {
+	_, err := NewWebNode("http://[::1", nil)
+	if err == nil || !strings.Contains(err.Error(), `invalid URL "http://[::1"`) {
+		t.Errorf("NewWebNode error = %v, want an invalid URL error", err)
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"github.com/qrtt1/doc-harvester/pkg/extractor"
+)
+
+// removeBoilerplate strips the blocks repeated on more than minShare of the pages from their content.
+// Pages that failed to fetch have no content and don't count.
+func removeBoilerplate(pages []XMLPage, minShare float64) int {
+	var contents []string
+	var indexes []int
+	for i, page := range pages {
+		if page.Error == "" {
+			contents = append(contents, string(page.Content))
+			indexes = append(indexes, i)
+		}
+	}
+
+	contents, removed := extractor.NewContentExtractor().RemoveBoilerplate(contents, minShare)
+	for j, i := range indexes {
+		pages[i].Content = CDATA(contents[j])
+	}
+	return removed
+}
+
+// RemoveBoilerplate strips blocks repeated on more than minShare of the pages, such as sidebars and footers,
+// and returns the number of blocks removed. Word counts and token estimates keep the values of the full content.
+func (s *XMLStorage) RemoveBoilerplate(minShare float64) int {
+	s.Document.mutex.Lock()
+	defer s.Document.mutex.Unlock()
+
+	return removeBoilerplate(s.Document.Pages, minShare)
+}
+
+// RemoveBoilerplate strips blocks repeated on more than minShare of the pages, like XMLStorage.RemoveBoilerplate
+func (s *MemoryStorage) RemoveBoilerplate(minShare float64) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return removeBoilerplate(s.pages, minShare)
+}
+
+// RemoveBoilerplate strips blocks repeated on more than minShare of the pages before they are converted to Markdown
+func (s *SingleMarkdownStorage) RemoveBoilerplate(minShare float64) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	contents := make([]string, len(s.pages))
+	for i, page := range s.pages {
+		contents[i] = page.Content
+	}
+
+	contents, removed := s.extractor.RemoveBoilerplate(contents, minShare)
+	for i := range s.pages {
+		s.pages[i].Content = contents[i]
+	}
+	return removed
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"crypto/sha256"
+)
+
+// DiffResult lists the pages that differ between two harvests
+type DiffResult struct {
+	Added   []string `json:"added"`   // URLs only present in the new harvest
+	Removed []string `json:"removed"` // URLs only present in the old harvest
+	Changed []string `json:"changed"` // URLs present in both whose content differs
+}
+
+// Empty reports whether both harvests contain the same pages with the same content
+func (d *DiffResult) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares two XML harvest files by page URL and content hash
+func Diff(oldPath, newPath string) (*DiffResult, error) {
+	oldDoc, err := LoadXMLDocument(oldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	newDoc, err := LoadXMLDocument(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	oldHashes := make(map[string][sha256.Size]byte)
+	for _, page := range oldDoc.Pages {
+		oldHashes[page.URL] = sha256.Sum256([]byte(page.Content))
+	}
+
+	result := &DiffResult{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	seen := make(map[string]bool)
+	for _, page := range newDoc.Pages {
+		seen[page.URL] = true
+		oldHash, ok := oldHashes[page.URL]
+		if !ok {
+			result.Added = append(result.Added, page.URL)
+		} else if oldHash != sha256.Sum256([]byte(page.Content)) {
+			result.Changed = append(result.Changed, page.URL)
+		}
+	}
+
+	for _, page := range oldDoc.Pages {
+		if !seen[page.URL] {
+			result.Removed = append(result.Removed, page.URL)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// JSONLStorage writes every page as one JSON object per line as soon as it is saved, for tools reading the
+// output while the crawl is running. Nothing is buffered, so a page saved again (e.g. after a retry) is written again.
+type JSONLStorage struct {
+	FilePath string // Path to the JSON Lines file; empty when writing to another writer
+
+	w     io.Writer
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// JSONLRecord is the line written for a page
+type JSONLRecord struct {
+	URL         string      `json:"url"`
+	Title       string      `json:"title"`
+	LastFetched string      `json:"lastFetched"`
+	Lang        string      `json:"lang,omitempty"`
+	Error       string      `json:"error,omitempty"` // Why the page could not be fetched; the content is empty
+	Content     string      `json:"content"`
+	Links       []JSONLLink `json:"links,omitempty"`
+}
+
+// JSONLLink is a link found on a page
+type JSONLLink struct {
+	URL  string `json:"url"`
+	Text string `json:"text,omitempty"`
+}
+
+// NewJSONLStorage creates a storage writing JSON Lines to filePath, replacing an existing file
+func NewJSONLStorage(filePath string) (*JSONLStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JSON Lines file: %v", err)
+	}
+
+	return &JSONLStorage{FilePath: filePath, w: file, file: file}, nil
+}
+
+// NewJSONLWriterStorage creates a storage writing JSON Lines to w, e.g. os.Stdout
+func NewJSONLWriterStorage(w io.Writer) *JSONLStorage {
+	return &JSONLStorage{w: w}
+}
+
+// SaveNodeContent writes the record of a page as one line
+func (s *JSONLStorage) SaveNodeContent(webNode *node.WebNode, content string) error {
+	page, err := NewXMLPage(webNode, content)
+	if err != nil {
+		return err
+	}
+
+	record := JSONLRecord{
+		URL:         page.URL,
+		Title:       page.Title,
+		LastFetched: page.LastFetched,
+		Lang:        page.Lang,
+		Error:       page.Error,
+		Content:     string(page.Content),
+	}
+	for _, link := range page.Links {
+		record.Links = append(record.Links, JSONLLink{URL: link.URL, Text: link.Text})
+	}
+
+	// Keep the markup of the content legible instead of escaping <, > and &
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to encode page: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.w.Write(line.Bytes()); err != nil {
+		return fmt.Errorf("failed to write JSON Lines record: %v", err)
+	}
+	return nil
+}
+
+// CreateIndexFile implements empty operation; records are written as pages are saved
+func (s *JSONLStorage) CreateIndexFile(path string) error {
+	return nil
+}
+
+// Close closes the JSON Lines file; a writer given to NewJSONLWriterStorage is left open
+func (s *JSONLStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close JSON Lines file: %v", err)
+	}
+	return nil
+}
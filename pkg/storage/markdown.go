@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/qrtt1/doc-harvester/pkg/extractor"
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// DefaultMarkdownSeparator separates pages in a consolidated Markdown document
+const DefaultMarkdownSeparator = "\n\n---\n\n"
+
+// markdownPage is a page collected by SingleMarkdownStorage
+type markdownPage struct {
+	URL        string
+	FetchedURL string // URL the page was fetched from, when it differs from URL
+	Slug       string
+	Title      string
+	Content    string // Extracted HTML, converted to Markdown by Close
+}
+
+// SingleMarkdownStorage collects all pages into one Markdown document, written when the storage is closed.
+// Pages appear in crawl order, each with a "# Title" header and a source URL footer.
+type SingleMarkdownStorage struct {
+	FilePath  string    // Path to the Markdown file
+	Separator string    // Text written between pages
+	Output    io.Writer // If set, the document is written here (e.g. os.Stdout) instead of to FilePath
+
+	// If set, links to other pages of the document point to the pages' headings, which get an {#id} attribute,
+	// so the document can be navigated offline; links to pages that were not harvested are kept
+	LocalLinks bool
+
+	extractor  *extractor.ContentExtractor
+	pages      []markdownPage
+	pagesByURL map[string]int
+	mutex      sync.Mutex
+}
+
+// NewSingleMarkdownStorage creates a storage writing a consolidated Markdown document to filePath
+func NewSingleMarkdownStorage(filePath string) (*SingleMarkdownStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	return &SingleMarkdownStorage{
+		FilePath:   filePath,
+		Separator:  DefaultMarkdownSeparator,
+		extractor:  extractor.NewContentExtractor(),
+		pagesByURL: make(map[string]int),
+	}, nil
+}
+
+// SaveNodeContent adds a page to the document, to be converted to Markdown by Close; pages that failed to fetch are left out
+func (s *SingleMarkdownStorage) SaveNodeContent(webNode *node.WebNode, content string) error {
+	if webNode == nil || webNode.URL == nil {
+		return fmt.Errorf("invalid node or URL")
+	}
+	if webNode.Metadata["error"] != "" {
+		return nil
+	}
+
+	urlStr := webNode.URL.String()
+	slug := webNode.Slug()
+	fetchedURL := ""
+	if canonical := webNode.Metadata["canonical"]; canonical != "" && canonical != urlStr {
+		fetchedURL = urlStr
+		urlStr = canonical
+		if canonicalURL, err := url.Parse(canonical); err == nil {
+			slug = node.URLSlug(canonicalURL)
+		}
+	}
+
+	page := markdownPage{
+		URL:        urlStr,
+		FetchedURL: fetchedURL,
+		Slug:       slug,
+		Title:      webNode.Title,
+		Content:    content,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if idx, exists := s.pagesByURL[urlStr]; exists {
+		s.pages[idx] = page
+	} else {
+		s.pages = append(s.pages, page)
+		s.pagesByURL[urlStr] = len(s.pages) - 1
+	}
+
+	return nil
+}
+
+// CreateIndexFile implements empty operation; the document is written by Close
+func (s *SingleMarkdownStorage) CreateIndexFile(path string) error {
+	return nil
+}
+
+// Close writes the consolidated Markdown document
+func (s *SingleMarkdownStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var anchors []string
+	if s.LocalLinks {
+		anchors = s.localizeLinks()
+	}
+
+	var sb strings.Builder
+	for i, page := range s.pages {
+		if i > 0 {
+			sb.WriteString(s.Separator)
+		}
+
+		title := page.Title
+		if title == "" {
+			title = page.URL
+		}
+		sb.WriteString("# " + strings.TrimSpace(title))
+		if anchors != nil {
+			sb.WriteString(" {#" + anchors[i] + "}")
+		}
+		sb.WriteString("\n\n")
+		if markdown := s.extractor.ConvertToMarkdown(page.Content); markdown != "" {
+			sb.WriteString(markdown + "\n\n")
+		}
+		sb.WriteString("Source: <" + page.URL + ">")
+	}
+	sb.WriteString("\n")
+
+	if s.Output != nil {
+		if _, err := io.WriteString(s.Output, sb.String()); err != nil {
+			return fmt.Errorf("failed to write Markdown: %v", err)
+		}
+		return nil
+	}
+
+	if err := os.WriteFile(s.FilePath, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write Markdown file: %v", err)
+	}
+
+	return nil
+}
+
+// localizeLinks points the links between the pages to the pages' headings, once all pages are known.
+// It returns the heading IDs of the pages, made from their slugs and unique within the document.
+func (s *SingleMarkdownStorage) localizeLinks() []string {
+	anchors := make([]string, len(s.pages))
+	byURL := make(map[string]string)
+	used := make(map[string]bool)
+	for i, page := range s.pages {
+		anchor := markdownAnchor(page.Slug)
+		for n := 2; used[anchor]; n++ {
+			anchor = fmt.Sprintf("%s-%d", markdownAnchor(page.Slug), n)
+		}
+		used[anchor] = true
+		anchors[i] = anchor
+
+		for _, pageURL := range []string{page.URL, page.FetchedURL} {
+			if pageURL, _, _ = strings.Cut(pageURL, "#"); pageURL != "" {
+				byURL[pageURL] = anchor
+			}
+		}
+	}
+
+	for i := range s.pages {
+		s.pages[i].Content = s.extractor.RewriteLinks(s.pages[i].Content, func(href string) string {
+			target, _, _ := strings.Cut(href, "#")
+			if anchor, ok := byURL[target]; ok {
+				return "#" + anchor
+			}
+			return href
+		})
+	}
+
+	return anchors
+}
+
+// markdownAnchor turns a page slug such as docs.example.com/guide/intro into a heading ID
+func markdownAnchor(slug string) string {
+	return strings.ToLower(strings.NewReplacer("/", "-", ".", "-").Replace(slug))
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// writeMarkdown returns the document a SingleMarkdownStorage writes for the given pages, in order
+func writeMarkdown(t *testing.T, localLinks bool, pages []markdownPage) string {
+	t.Helper()
+	s, err := NewSingleMarkdownStorage(filepath.Join(t.TempDir(), "docs.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s.Output = &out
+	s.LocalLinks = localLinks
+
+	for _, page := range pages {
+		webNode, err := node.NewWebNode(page.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		webNode.Title = page.Title
+		if err := s.SaveNodeContent(webNode, page.Content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestLocalLinks(t *testing.T) {
+	// Two pages linking to each other, and to a page that was not harvested
+	pages := []markdownPage{
+		{
+			URL:     "https://example.org/docs/intro.html",
+			Title:   "Intro",
+			Content: `<p>Read the <a href="https://example.org/docs/guide.html#setup">guide</a> or the <a href="https://example.org/blog/">blog</a>.</p>`,
+		},
+		{
+			URL:     "https://example.org/docs/guide.html",
+			Title:   "Guide",
+			Content: `<p>Back to the <a href="https://example.org/docs/intro.html">intro</a>.</p>`,
+		},
+	}
+
+	markdown := writeMarkdown(t, true, pages)
+	for _, want := range []string{
+		"# Intro {#example-org-docs-intro-html}\n",
+		"# Guide {#example-org-docs-guide-html}\n",
+		"[guide](#example-org-docs-guide-html)",
+		"[intro](#example-org-docs-intro-html)",
+		"[blog](https://example.org/blog/)",
+		"Source: <https://example.org/docs/guide.html>",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("document lacks %q:\n%s", want, markdown)
+		}
+	}
+
+	// Without LocalLinks, the links and headings are left alone
+	markdown = writeMarkdown(t, false, pages)
+	for _, want := range []string{
+		"# Intro\n",
+		"[guide](https://example.org/docs/guide.html#setup)",
+		"[intro](https://example.org/docs/intro.html)",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("document lacks %q:\n%s", want, markdown)
+		}
+	}
+}
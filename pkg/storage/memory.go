@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// MemoryStorage keeps harvested pages in memory, for tests and programs embedding the harvester.
+// Pages have the same form as in XML output; nothing is written to disk.
+type MemoryStorage struct {
+	pages      []XMLPage
+	pagesByURL map[string]int
+	mutex      sync.Mutex
+}
+
+// NewMemoryStorage creates an empty in-memory storage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{pagesByURL: make(map[string]int)}
+}
+
+// SaveNodeContent adds a page, replacing an earlier page with the same URL
+func (s *MemoryStorage) SaveNodeContent(webNode *node.WebNode, content string) error {
+	page, err := NewXMLPage(webNode, content)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if idx, exists := s.pagesByURL[page.URL]; exists {
+		s.pages[idx] = page
+	} else {
+		s.pages = append(s.pages, page)
+		s.pagesByURL[page.URL] = len(s.pages) - 1
+	}
+
+	return nil
+}
+
+// CreateIndexFile implements an empty method, as there are no files
+func (s *MemoryStorage) CreateIndexFile(path string) error {
+	return nil
+}
+
+// Pages returns a copy of the stored pages in the order they were first saved
+func (s *MemoryStorage) Pages() []XMLPage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]XMLPage(nil), s.pages...)
+}
+
+// Page returns the stored page with the given URL
+func (s *MemoryStorage) Page(url string) (XMLPage, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx, ok := s.pagesByURL[url]
+	if !ok {
+		return XMLPage{}, false
+	}
+	return s.pages[idx], true
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Merge combines harvest XML files into one, written to output.
+// Pages are deduplicated by URL, keeping the one fetched last; the others keep the order in which they first appear.
+// The root URL of the first input is used, and the root URLs of all inputs are listed as seeds.
+func Merge(inputs []string, output string) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no files to merge")
+	}
+
+	merged := &XMLDocument{
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Pages:      make([]XMLPage, 0),
+		pagesByURL: make(map[string]int),
+	}
+	seen := make(map[string]bool)
+	for i, input := range inputs {
+		doc, err := LoadXMLDocument(input)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			merged.RootURL = doc.RootURL
+		}
+
+		seeds := doc.SeedURLs
+		if len(seeds) == 0 {
+			seeds = []string{doc.RootURL}
+		}
+		for _, seed := range seeds {
+			if seed != "" && !seen[seed] {
+				seen[seed] = true
+				merged.SeedURLs = append(merged.SeedURLs, seed)
+			}
+		}
+
+		for _, page := range doc.Pages {
+			idx, exists := merged.pagesByURL[page.URL]
+			if !exists {
+				merged.Pages = append(merged.Pages, page)
+				merged.pagesByURL[page.URL] = len(merged.Pages) - 1
+				continue
+			}
+			if fetchedAfter(page, merged.Pages[idx]) {
+				merged.Pages[idx] = page
+			}
+		}
+	}
+
+	// A single root is already named by the rootUrl attribute
+	if len(merged.SeedURLs) < 2 {
+		merged.SeedURLs = nil
+	}
+
+	s := &XMLStorage{FilePath: output, Document: merged}
+	return s.SaveToFile()
+}
+
+// fetchedAfter reports whether page a was fetched later than page b; pages with an unreadable time count as oldest
+func fetchedAfter(a, b XMLPage) bool {
+	timeA, errA := time.Parse(time.RFC3339, a.LastFetched)
+	timeB, errB := time.Parse(time.RFC3339, b.LastFetched)
+	switch {
+	case errA != nil:
+		return false
+	case errB != nil:
+		return true
+	}
+	return timeA.After(timeB)
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// SitemapNamespace is the XML namespace of the sitemap protocol
+const SitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// sitemapURLSet is the root element of a sitemap.xml file
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapURL is a page listed in a sitemap
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// GenerateSitemap writes a sitemap.xml of the stored pages that were fetched successfully,
+// using the time each page was fetched as its lastmod
+func (s *XMLStorage) GenerateSitemap(w io.Writer) error {
+	s.Document.mutex.Lock()
+	urlSet := sitemapURLSet{Xmlns: SitemapNamespace}
+	for _, page := range s.Document.Pages {
+		if page.Error != "" {
+			continue
+		}
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: page.URL, LastMod: page.LastFetched})
+	}
+	s.Document.mutex.Unlock()
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write sitemap: %v", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(urlSet); err != nil {
+		return fmt.Errorf("failed to write sitemap: %v", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write sitemap: %v", err)
+	}
+
+	return nil
+}
@@ -1,10 +1,16 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,20 +21,74 @@ import (
 type XMLDocument struct {
 	XMLName    xml.Name       `xml:"document"`
 	RootURL    string         `xml:"rootUrl,attr"`
+	SeedURLs   []string       `xml:"seeds>seed,omitempty"` // All seed URLs, when crawling from more than one
 	CreatedAt  string         `xml:"createdAt,attr"`
 	Pages      []XMLPage      `xml:"page"`
 	pagesByURL map[string]int // Maps URL -> Pages array index for fast lookup
 	mutex      sync.Mutex     // Ensures thread safety
+
+	pageElement string // Name of the page elements of a loaded document
 }
 
 // XMLPage represents the content of a single page
 type XMLPage struct {
-	URL         string   `xml:"url,attr"`
-	Title       string   `xml:"title,attr"`
-	Path        string   `xml:"path,attr"`
-	LastFetched string   `xml:"lastFetched,attr"`
-	Content     string   `xml:"content"`
-	Links       []string `xml:"links>link,omitempty"`
+	URL         string        `xml:"url,attr"`
+	FetchedURL  string        `xml:"fetchedUrl,attr,omitempty"` // URL the page was fetched from, when it differs from its canonical URL
+	Title       string        `xml:"title,attr"`
+	Path        string        `xml:"path,attr"` // Filesystem-safe slug of the URL: host, path and a hash of the query
+	LastFetched string        `xml:"lastFetched,attr"`
+	PublishedAt string        `xml:"publishedAt,attr,omitempty"` // Publication date shown on the page (RFC 3339)
+	UpdatedAt   string        `xml:"updatedAt,attr,omitempty"`   // Last update date shown on the page (RFC 3339)
+	WordCount   int           `xml:"wordCount,attr,omitempty"`
+	ReadingTime int           `xml:"readingTime,attr,omitempty"` // Estimated reading time in minutes
+	Tokens      int           `xml:"tokens,attr,omitempty"`      // Estimated number of language model tokens
+	Truncated   bool          `xml:"truncated,attr,omitempty"`   // Whether the content was cut to the maximum length
+	RequiresJS  bool          `xml:"requiresJs,attr,omitempty"`  // Whether the page looks rendered by JavaScript, so its content may be missing
+	FetchMs     int           `xml:"fetchMs,attr,omitempty"`     // How long fetching the page took, in milliseconds
+	Extraction  string        `xml:"extraction,attr,omitempty"`  // Extraction strategy that produced the content, such as "main" or "body"
+	Lang        string        `xml:"lang,attr,omitempty"`
+	Description string        `xml:"description,attr,omitempty"`
+	Excerpt     string        `xml:"excerpt,attr,omitempty"` // Text of the first paragraphs, for previews
+	Author      string        `xml:"author,attr,omitempty"`
+	OGTitle     string        `xml:"ogTitle,attr,omitempty"`
+	OGDesc      string        `xml:"ogDescription,attr,omitempty"`
+	OGImage     string        `xml:"ogImage,attr,omitempty"`
+	Error       string        `xml:"error,attr,omitempty"` // Why the page could not be fetched; the content is empty
+	Content     CDATA         `xml:"content"`
+	RawContent  CDATA         `xml:"rawContent,omitempty"` // Fetched HTML of the page before extraction, with -dump-raw
+	Links       []XMLLink     `xml:"links>link,omitempty"`
+	Downloads   []XMLDownload `xml:"downloads>download,omitempty"`
+	Anchors     []XMLAnchor   `xml:"anchors>anchor,omitempty"`
+}
+
+// XMLAnchor is a link target in the content of a page, for links such as url#id
+type XMLAnchor struct {
+	ID   string `xml:"id,attr"`
+	Text string `xml:"text,attr"` // Text of the heading
+}
+
+// XMLLink is a link found on a page
+type XMLLink struct {
+	URL  string `xml:"url,attr"`
+	Text string `xml:"text,attr,omitempty"` // Visible anchor text
+}
+
+// XMLDownload is a link to a downloadable file found on a page
+type XMLDownload struct {
+	URL  string `xml:"url,attr"`
+	Text string `xml:"text,attr,omitempty"`
+	Path string `xml:"path,attr,omitempty"` // Stored file, relative to the XML file, if it was downloaded
+}
+
+// CDATA is text marshaled as a CDATA section, so stored HTML stays legible instead of being escaped.
+// It unmarshals like any other character data.
+type CDATA string
+
+// MarshalXML implements xml.Marshaler
+func (c CDATA) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		Text string `xml:",cdata"`
+	}{string(c)}, start)
 }
 
 // XMLStorage manages downloaded content as a single XML file
@@ -37,8 +97,39 @@ type XMLStorage struct {
 	Document     *XMLDocument  // XML document object
 	SaveInterval time.Duration // Auto-save interval
 	stopAutoSave chan bool     // Channel to stop auto-save
+	saveNow      chan struct{} // Requests an auto-save before the interval is over
+	Logger       *slog.Logger  // Logger for auto-save errors
+	FilesDir     string        // Directory, relative to the XML file, where SaveFile stores files
+	savedFiles   map[string]bool
+
+	// Names of the root and page elements, for pipelines with a fixed schema; empty means DefaultRootElement and DefaultPageElement.
+	// LoadXMLDocument reads files with any names, and OpenXMLStorage keeps the names of the file it opens.
+	RootElement string
+	PageElement string
+
+	// If set, the document is written to Output (e.g. os.Stdout) once, when the storage is closed, instead of to FilePath
+	Output io.Writer
+
+	// Also auto-save once this many pages were added since the last save, so a crash loses little work (0 means only on the interval)
+	SaveEveryNPages int
+	unsavedPages    int // Pages added since the last save, guarded by the document's mutex
 }
 
+// DefaultSaveEveryNPages is the number of new pages after which a new XMLStorage saves, unless the interval comes first
+const DefaultSaveEveryNPages = 50
+
+// Default element names of the XML output
+const (
+	DefaultRootElement = "document"
+	DefaultPageElement = "page"
+)
+
+// Directories for files stored next to the XML file
+const (
+	DefaultFilesDir = "files"  // Downloaded files of a plain XML output
+	AssetsDir       = "assets" // Images and files of an output directory
+)
+
 // NewXMLStorage creates a new XML storage manager
 func NewXMLStorage(filePath string, rootURL string) (*XMLStorage, error) {
 	// Ensure directory exists
@@ -55,20 +146,44 @@ func NewXMLStorage(filePath string, rootURL string) (*XMLStorage, error) {
 		pagesByURL: make(map[string]int),
 	}
 
+	return newXMLStorage(filePath, doc), nil
+}
+
+// OpenXMLStorage creates an XML storage continuing the document of an existing file,
+// so saved pages replace the ones with the same URL and the file is updated in place
+func OpenXMLStorage(filePath string) (*XMLStorage, error) {
+	doc, err := LoadXMLDocument(filePath)
+	if err != nil {
+		return nil, err
+	}
+	storage := newXMLStorage(filePath, doc)
+	storage.RootElement = doc.XMLName.Local
+	storage.PageElement = doc.pageElement
+	return storage, nil
+}
+
+// newXMLStorage creates an XML storage for a document and starts auto-saving it
+func newXMLStorage(filePath string, doc *XMLDocument) *XMLStorage {
 	storage := &XMLStorage{
 		FilePath:     filePath,
 		Document:     doc,
 		SaveInterval: 5 * time.Minute, // Default auto-save every 5 minutes
 		stopAutoSave: make(chan bool),
+		saveNow:      make(chan struct{}, 1),
+		Logger:       slog.Default(),
+		FilesDir:     DefaultFilesDir,
+		savedFiles:   make(map[string]bool),
+
+		SaveEveryNPages: DefaultSaveEveryNPages,
 	}
 
 	// Start auto-save
 	go storage.autoSaveLoop()
 
-	return storage, nil
+	return storage
 }
 
-// autoSaveLoop periodically auto-saves the XML document
+// autoSaveLoop periodically auto-saves the XML document, and whenever SaveEveryNPages new pages ask for it
 func (s *XMLStorage) autoSaveLoop() {
 	ticker := time.NewTicker(s.SaveInterval)
 	defer ticker.Stop()
@@ -76,12 +191,22 @@ func (s *XMLStorage) autoSaveLoop() {
 	for {
 		select {
 		case <-ticker.C:
-			if err := s.SaveToFile(); err != nil {
-				fmt.Printf("Error during auto-save: %v\n", err)
-			}
+		case <-s.saveNow:
 		case <-s.stopAutoSave:
 			return
 		}
+		if err := s.SaveToFile(); err != nil {
+			s.Logger.Error("Error during auto-save", "error", err)
+		}
+	}
+}
+
+// requestSave asks the auto-save loop to save soon, without waiting for it.
+// Requests made while one is pending are merged into it.
+func (s *XMLStorage) requestSave() {
+	select {
+	case s.saveNow <- struct{}{}:
+	default:
 	}
 }
 
@@ -90,48 +215,283 @@ func (s *XMLStorage) StopAutoSave() {
 	s.stopAutoSave <- true
 }
 
-// SaveToFile saves the XML document to a file
+// Close stops auto-saving and saves the XML document one last time
+func (s *XMLStorage) Close() error {
+	s.StopAutoSave()
+	if s.Output != nil {
+		return s.WriteDocument(s.Output)
+	}
+	return s.SaveToFile()
+}
+
+// SaveToFile saves the XML document to a file.
+// With Output set, the document is only written when the storage is closed, so nothing is saved.
 func (s *XMLStorage) SaveToFile() error {
+	if s.Output != nil {
+		return nil
+	}
+
+	xmlData, err := s.encode()
+	if err != nil {
+		return err
+	}
+
+	// Write to file
+	if err := os.WriteFile(s.FilePath, xmlData, 0644); err != nil {
+		return fmt.Errorf("failed to write XML file: %v", err)
+	}
+
+	return nil
+}
+
+// WriteDocument writes the XML document to w
+func (s *XMLStorage) WriteDocument(w io.Writer) error {
+	xmlData, err := s.encode()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(xmlData); err != nil {
+		return fmt.Errorf("failed to write XML: %v", err)
+	}
+
+	return nil
+}
+
+// encode encodes the XML document with its XML header and prompt reference comment
+func (s *XMLStorage) encode() ([]byte, error) {
 	s.Document.mutex.Lock()
 	defer s.Document.mutex.Unlock()
 
 	// Encode document as XML
-	xmlData, err := xml.MarshalIndent(s.Document, "", "  ")
+	xmlData, err := s.marshalDocument()
 	if err != nil {
-		return fmt.Errorf("failed to marshal XML: %v", err)
+		return nil, fmt.Errorf("failed to marshal XML: %v", err)
 	}
+	s.unsavedPages = 0
 
 	// Add XML header
 	// Add prompt reference data tag
 	xmlData = append([]byte("<!-- PROMPT_REFERENCE_DATA: Web documentation harvested by DocHarvester, intended for use as reference material in prompts and context windows -->\n"), xmlData...)
 	xmlData = append([]byte(xml.Header), xmlData...)
 
-	// Write to file
-	if err := os.WriteFile(s.FilePath, xmlData, 0644); err != nil {
-		return fmt.Errorf("failed to write XML file: %v", err)
+	return xmlData, nil
+}
+
+// marshalDocument encodes the document like xml.MarshalIndent would, with the root and page elements named as configured
+func (s *XMLStorage) marshalDocument() ([]byte, error) {
+	rootElement, pageElement := s.RootElement, s.PageElement
+	if rootElement == "" {
+		rootElement = DefaultRootElement
+	}
+	if pageElement == "" {
+		pageElement = DefaultPageElement
 	}
 
-	return nil
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+
+	start := xml.StartElement{
+		Name: xml.Name{Local: rootElement},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "rootUrl"}, Value: s.Document.RootURL},
+			{Name: xml.Name{Local: "createdAt"}, Value: s.Document.CreatedAt},
+		},
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return nil, err
+	}
+	if len(s.Document.SeedURLs) > 0 {
+		seeds := struct {
+			Seeds []string `xml:"seed"`
+		}{s.Document.SeedURLs}
+		if err := encoder.EncodeElement(seeds, xml.StartElement{Name: xml.Name{Local: "seeds"}}); err != nil {
+			return nil, err
+		}
+	}
+	for _, page := range s.Document.Pages {
+		if err := encoder.EncodeElement(page, xml.StartElement{Name: xml.Name{Local: pageElement}}); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.EncodeToken(start.End()); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// LoadXMLDocument reads a document previously written by SaveToFile.
+// The root and page elements may have any name: the root is the first element and every child other than <seeds> is a page.
+func LoadXMLDocument(path string) (*XMLDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XML file: %v", err)
+	}
+
+	doc, err := decodeXMLDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XML file %s: %v", path, err)
+	}
+
+	doc.pagesByURL = make(map[string]int)
+	for i, page := range doc.Pages {
+		doc.pagesByURL[page.URL] = i
+	}
+
+	return doc, nil
+}
+
+// decodeXMLDocument decodes a document whatever the names of its root and page elements,
+// which are recorded in XMLName and pageElement
+func decodeXMLDocument(data []byte) (*XMLDocument, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	doc := &XMLDocument{}
+
+	var root *xml.StartElement
+	for root == nil {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = &start
+		}
+	}
+
+	doc.XMLName = root.Name
+	for _, attr := range root.Attr {
+		switch attr.Name.Local {
+		case "rootUrl":
+			doc.RootURL = attr.Value
+		case "createdAt":
+			doc.CreatedAt = attr.Value
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "seeds" {
+				var seeds struct {
+					Seeds []string `xml:"seed"`
+				}
+				if err := decoder.DecodeElement(&seeds, &t); err != nil {
+					return nil, err
+				}
+				doc.SeedURLs = append(doc.SeedURLs, seeds.Seeds...)
+				continue
+			}
+
+			var page XMLPage
+			if err := decoder.DecodeElement(&page, &t); err != nil {
+				return nil, err
+			}
+			if doc.pageElement == "" {
+				doc.pageElement = t.Name.Local
+			}
+			doc.Pages = append(doc.Pages, page)
+		case xml.EndElement:
+			return doc, nil
+		}
+	}
+}
+
+// FailedURLs returns the URLs of the pages recorded as failed, in document order
+func (d *XMLDocument) FailedURLs() []string {
+	var urls []string
+	for _, page := range d.Pages {
+		if page.Error != "" {
+			urls = append(urls, page.URL)
+		}
+	}
+	return urls
+}
+
+// removePage removes the page at index idx; the caller holds the document's mutex
+func (d *XMLDocument) removePage(idx int) {
+	delete(d.pagesByURL, d.Pages[idx].URL)
+	d.Pages = append(d.Pages[:idx], d.Pages[idx+1:]...)
+	for i := idx; i < len(d.Pages); i++ {
+		d.pagesByURL[d.Pages[i].URL] = i
+	}
 }
 
 // SaveNodeContent saves node content to the XML document
 func (s *XMLStorage) SaveNodeContent(webNode *node.WebNode, content string) error {
-	if webNode == nil || webNode.URL == nil {
-		return fmt.Errorf("invalid node or URL")
+	page, err := NewXMLPage(webNode, content)
+	if err != nil {
+		return err
 	}
 
-	urlStr := webNode.URL.String()
-	path := webNode.URL.Path
-
 	s.Document.mutex.Lock()
 	defer s.Document.mutex.Unlock()
 
-	// Extract all links from the current page
-	var links []string
-	if webNode.Children != nil {
+	// Check if page already exists
+	if idx, exists := s.Document.pagesByURL[page.URL]; exists {
+		// Update existing page
+		s.Document.Pages[idx] = page
+	} else {
+		// Add new page
+		s.Document.Pages = append(s.Document.Pages, page)
+		s.Document.pagesByURL[page.URL] = len(s.Document.Pages) - 1
+	}
+
+	// A failed fetch recorded under the fetched URL is replaced by the page stored under its canonical URL
+	if page.FetchedURL != "" && page.Error == "" {
+		if idx, exists := s.Document.pagesByURL[page.FetchedURL]; exists && s.Document.Pages[idx].Error != "" {
+			s.Document.removePage(idx)
+		}
+	}
+
+	// Save early once enough pages are new; saving resets the count
+	s.unsavedPages++
+	if s.SaveEveryNPages > 0 && s.unsavedPages >= s.SaveEveryNPages {
+		s.requestSave()
+	}
+
+	return nil
+}
+
+// NewXMLPage creates the stored form of a harvested page, with the statistics and metadata the harvester recorded.
+// Pages are keyed by their canonical URL, so aliases collapse into one entry.
+func NewXMLPage(webNode *node.WebNode, content string) (XMLPage, error) {
+	if webNode == nil || webNode.URL == nil {
+		return XMLPage{}, fmt.Errorf("invalid node or URL")
+	}
+
+	urlStr := webNode.URL.String()
+	path := webNode.Slug()
+	fetchedURL := ""
+	if canonical := webNode.Metadata["canonical"]; canonical != "" && canonical != urlStr {
+		fetchedURL = urlStr
+		urlStr = canonical
+		if canonicalURL, err := url.Parse(canonical); err == nil {
+			path = node.URLSlug(canonicalURL)
+		}
+	}
+
+	// Use the links found on the page; without them, fall back to the page's children in the tree
+	var links []XMLLink
+	if webNode.Links != nil {
+		for _, link := range webNode.Links {
+			links = append(links, XMLLink{URL: link.URL, Text: link.Text})
+		}
+	} else {
 		for _, child := range webNode.Children {
 			if child.URL != nil {
-				links = append(links, child.URL.String())
+				links = append(links, XMLLink{URL: child.URL.String()})
 			}
 		}
 	}
@@ -139,24 +499,69 @@ func (s *XMLStorage) SaveNodeContent(webNode *node.WebNode, content string) erro
 	// Create page object
 	page := XMLPage{
 		URL:         urlStr,
+		FetchedURL:  fetchedURL,
 		Title:       webNode.Title,
 		Path:        path,
 		LastFetched: time.Now().Format(time.RFC3339),
-		Content:     content,
+		Content:     CDATA(content),
 		Links:       links,
 	}
+	for _, download := range webNode.Downloads {
+		page.Downloads = append(page.Downloads, XMLDownload{URL: download.URL, Text: download.Text, Path: download.Path})
+	}
+	for _, anchor := range webNode.Anchors {
+		page.Anchors = append(page.Anchors, XMLAnchor{ID: anchor.ID, Text: anchor.Text})
+	}
 
-	// Check if page already exists
-	if idx, exists := s.Document.pagesByURL[urlStr]; exists {
-		// Update existing page
-		s.Document.Pages[idx] = page
-	} else {
-		// Add new page
-		s.Document.Pages = append(s.Document.Pages, page)
-		s.Document.pagesByURL[urlStr] = len(s.Document.Pages) - 1
+	// Copy content statistics collected by the harvester
+	page.WordCount, _ = strconv.Atoi(webNode.Metadata["wordCount"])
+	page.ReadingTime, _ = strconv.Atoi(webNode.Metadata["readingTime"])
+	page.Tokens, _ = strconv.Atoi(webNode.Metadata["tokens"])
+	page.Truncated = webNode.Metadata["truncated"] == "true"
+	page.RequiresJS = webNode.Metadata["requiresJs"] == "true"
+	page.FetchMs, _ = strconv.Atoi(webNode.Metadata["fetchMs"])
+	page.Extraction = webNode.Metadata["extraction"]
+	page.Lang = webNode.Metadata["lang"]
+	page.PublishedAt = webNode.Metadata["publishedAt"]
+	page.UpdatedAt = webNode.Metadata["updatedAt"]
+	page.Description = webNode.Metadata["description"]
+	page.Excerpt = webNode.Metadata["excerpt"]
+	page.Author = webNode.Metadata["author"]
+	page.OGTitle = webNode.Metadata["og:title"]
+	page.OGDesc = webNode.Metadata["og:description"]
+	page.OGImage = webNode.Metadata["og:image"]
+	page.Error = webNode.Metadata["error"]
+	page.RawContent = CDATA(webNode.Metadata["rawContent"])
+
+	return page, nil
+}
+
+// SaveFile writes a file to FilesDir next to the XML file and returns its path relative to the XML file.
+// Names already used in this run get a numeric suffix, so files of the same name don't overwrite each other.
+func (s *XMLStorage) SaveFile(name string, data []byte) (string, error) {
+	s.Document.mutex.Lock()
+	defer s.Document.mutex.Unlock()
+
+	name = filepath.Base(name)
+	if name == "." || name == "/" {
+		name = "file"
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; s.savedFiles[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
 	}
 
-	return nil
+	dir := filepath.Join(filepath.Dir(s.FilePath), s.FilesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+	s.savedFiles[name] = true
+
+	return filepath.ToSlash(filepath.Join(s.FilesDir, name)), nil
 }
 
 // CreateIndexFile implements an empty method for XML format, as index files are not needed
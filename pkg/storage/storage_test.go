@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// saveTestDocument writes a document with the given pages (URL -> content) through an XMLStorage
+func saveTestDocument(t *testing.T, configure func(s *XMLStorage), pages map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docs.xml")
+	s, err := NewXMLStorage(path, "https://example.org/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configure != nil {
+		configure(s)
+	}
+
+	for pageURL, content := range pages {
+		webNode, err := node.NewWebNode(pageURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		webNode.Metadata["title"] = "Title of " + pageURL
+		if err := s.SaveNodeContent(webNode, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCustomElementNamesRoundTrip(t *testing.T) {
+	path := saveTestDocument(t, func(s *XMLStorage) {
+		s.RootElement = "corpus"
+		s.PageElement = "doc"
+		s.Document.SeedURLs = []string{"https://example.org/docs/", "https://example.org/blog/"}
+	}, map[string]string{"https://example.org/docs/intro": "<p>Intro</p>"})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<corpus ", "</corpus>", "<doc ", "</doc>"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output lacks %q:\n%s", want, data)
+		}
+	}
+
+	doc, err := LoadXMLDocument(path)
+	if err != nil {
+		t.Fatalf("LoadXMLDocument: %v", err)
+	}
+	if doc.RootURL != "https://example.org/docs/" || len(doc.SeedURLs) != 2 {
+		t.Errorf("loaded root URL %q and seeds %v", doc.RootURL, doc.SeedURLs)
+	}
+	if len(doc.Pages) != 1 || doc.Pages[0].URL != "https://example.org/docs/intro" || doc.Pages[0].Content != "<p>Intro</p>" {
+		t.Fatalf("loaded pages = %+v", doc.Pages)
+	}
+
+	// Reopened files keep their element names
+	s, err := OpenXMLStorage(path)
+	if err != nil {
+		t.Fatalf("OpenXMLStorage: %v", err)
+	}
+	if s.RootElement != "corpus" || s.PageElement != "doc" {
+		t.Errorf("reopened element names = %q, %q; want corpus, doc", s.RootElement, s.PageElement)
+	}
+	s.StopAutoSave()
+}
+
+func TestDefaultElementNamesRoundTrip(t *testing.T) {
+	path := saveTestDocument(t, nil, map[string]string{"https://example.org/docs/intro": "<p>Intro</p>"})
+
+	doc, err := LoadXMLDocument(path)
+	if err != nil {
+		t.Fatalf("LoadXMLDocument: %v", err)
+	}
+	if doc.XMLName.Local != DefaultRootElement || doc.pageElement != DefaultPageElement || len(doc.Pages) != 1 {
+		t.Errorf("loaded <%s> with %d <%s> pages", doc.XMLName.Local, len(doc.Pages), doc.pageElement)
+	}
+}
+
+func TestContentCDATARoundTrip(t *testing.T) {
+	contents := map[string]string{
+		"https://example.org/docs/html":  `<p>Use <code>a &lt; b</code> &amp; "quotes"</p>`,
+		"https://example.org/docs/cdata": `<pre>if (a[b[0]]>1) { x = "]]>"; }</pre><p>]]]]></p>`,
+	}
+	path := saveTestDocument(t, nil, contents)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<![CDATA[<p>Use <code>") {
+		t.Errorf("content is not written as CDATA:\n%s", data)
+	}
+
+	doc, err := LoadXMLDocument(path)
+	if err != nil {
+		t.Fatalf("LoadXMLDocument: %v", err)
+	}
+	for pageURL, want := range contents {
+		idx, ok := doc.pagesByURL[pageURL]
+		if !ok {
+			t.Errorf("page %s was not loaded", pageURL)
+			continue
+		}
+		if got := string(doc.Pages[idx].Content); got != want {
+			t.Errorf("content of %s = %q, want %q", pageURL, got, want)
+		}
+	}
+}
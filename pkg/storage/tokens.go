@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"math"
+	"strings"
+)
+
+// TokenEstimator estimates the number of language model tokens a text uses
+type TokenEstimator func(text string) int
+
+// tokensPerWord is the ratio used by EstimateTokens
+const tokensPerWord = 1.3
+
+// EstimateTokens is the default TokenEstimator: about 1.3 tokens per word
+func EstimateTokens(text string) int {
+	return int(math.Ceil(float64(len(strings.Fields(text))) * tokensPerWord))
+}
+
+// EstimatedTokens returns the total of the per-page token estimates of a document
+func (d *XMLDocument) EstimatedTokens() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	total := 0
+	for _, page := range d.Pages {
+		total += page.Tokens
+	}
+	return total
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// ValidateFile checks that an XML file can be loaded as a harvest document.
+// Parse errors report their line and column; the document is also checked for
+// duplicate page URLs and missing required attributes. All problems found are returned together.
+func ValidateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read XML file: %v", err)
+	}
+
+	doc := &XMLDocument{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(doc); err != nil {
+		line, column := decoder.InputPos()
+		return fmt.Errorf("%s:%d:%d: %v", path, line, column, err)
+	}
+
+	var problems []error
+	if doc.XMLName.Local != "document" {
+		problems = append(problems, fmt.Errorf("root element is <%s>, expected <document>", doc.XMLName.Local))
+	}
+	if doc.RootURL == "" {
+		problems = append(problems, fmt.Errorf("document has no rootUrl attribute"))
+	}
+
+	seen := make(map[string]int)
+	for i, page := range doc.Pages {
+		position := i + 1
+		if page.URL == "" {
+			problems = append(problems, fmt.Errorf("page %d has no url attribute", position))
+			continue
+		}
+		if _, err := url.Parse(page.URL); err != nil {
+			problems = append(problems, fmt.Errorf("page %d has an invalid url: %v", position, err))
+		}
+		if first, ok := seen[page.URL]; ok {
+			problems = append(problems, fmt.Errorf("page %d duplicates the url of page %d: %s", position, first, page.URL))
+		} else {
+			seen[page.URL] = position
+		}
+		if page.LastFetched == "" {
+			problems = append(problems, fmt.Errorf("page %d (%s) has no lastFetched attribute", position, page.URL))
+		}
+	}
+
+	return errors.Join(problems...)
+}
@@ -0,0 +1,164 @@
+package tree
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// Buckets of a DiskFrontier database
+var (
+	visitedBucket = []byte("visited")
+	pendingBucket = []byte("pending")
+)
+
+// DiskFrontier is a FrontierStore kept in a bbolt database file, so memory doesn't grow with the size of the crawl.
+// Pending pages are stored with their URL, depth and metadata only; popped pages are new nodes without a parent.
+// The file is scratch space for one crawl: an existing file is replaced, and writes are not synced to disk.
+type DiskFrontier struct {
+	db      *bolt.DB
+	pending int
+}
+
+// diskEntry is a pending page as stored by DiskFrontier
+type diskEntry struct {
+	URL      string            `json:"url"`
+	Depth    int               `json:"depth"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewDiskFrontier creates an empty frontier in the file at path
+func NewDiskFrontier(path string) (*DiskFrontier, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to replace frontier file: %v", err)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second, NoSync: true, NoFreelistSync: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frontier file: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{visitedBucket, pendingBucket} {
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize frontier file: %v", err)
+	}
+
+	return &DiskFrontier{db: db}, nil
+}
+
+// MarkVisited records a normalized URL, reporting whether it was recorded before
+func (f *DiskFrontier) MarkVisited(key string) (bool, error) {
+	visited := false
+	err := f.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(visitedBucket)
+		if b.Get([]byte(key)) != nil {
+			visited = true
+			return nil
+		}
+		return b.Put([]byte(key), []byte{})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record visited URL: %v", err)
+	}
+	return visited, nil
+}
+
+// IsVisited reports whether a normalized URL was recorded
+func (f *DiskFrontier) IsVisited(key string) (bool, error) {
+	visited := false
+	err := f.db.View(func(tx *bolt.Tx) error {
+		visited = tx.Bucket(visitedBucket).Get([]byte(key)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to look up visited URL: %v", err)
+	}
+	return visited, nil
+}
+
+// Push adds a page to the pending pages
+func (f *DiskFrontier) Push(n *node.WebNode) error {
+	data, err := json.Marshal(diskEntry{URL: n.URL.String(), Depth: n.Depth, Metadata: n.Metadata})
+	if err != nil {
+		return fmt.Errorf("failed to encode pending page: %v", err)
+	}
+
+	err = f.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(pendingBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return b.Put(key, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store pending page: %v", err)
+	}
+
+	f.pending++
+	return nil
+}
+
+// Pop removes the oldest pending page, or the newest one if newest is set
+func (f *DiskFrontier) Pop(newest bool) (*node.WebNode, error) {
+	var data []byte
+	err := f.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(pendingBucket).Cursor()
+		key, value := c.First()
+		if newest {
+			key, value = c.Last()
+		}
+		if key == nil {
+			return nil
+		}
+		data = append([]byte(nil), value...)
+		return c.Delete()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending page: %v", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	f.pending--
+
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode pending page: %v", err)
+	}
+	n, err := node.NewWebNode(entry.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	n.Depth = entry.Depth
+	for key, value := range entry.Metadata {
+		n.Metadata[key] = value
+	}
+	return n, nil
+}
+
+// Len returns the number of pending pages
+func (f *DiskFrontier) Len() int {
+	return f.pending
+}
+
+// Close closes the database file; it is left on disk
+func (f *DiskFrontier) Close() error {
+	return f.db.Close()
+}
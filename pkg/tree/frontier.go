@@ -0,0 +1,85 @@
+package tree
+
+import (
+	"github.com/qrtt1/doc-harvester/pkg/node"
+)
+
+// FrontierStore keeps the state of a crawl that grows with the site: the set of URLs seen so far
+// and the pages waiting to be fetched. MemoryFrontier is the default; DiskFrontier keeps both in a file.
+type FrontierStore interface {
+	// MarkVisited records a normalized URL, reporting whether it was recorded before
+	MarkVisited(key string) (bool, error)
+	// IsVisited reports whether a normalized URL was recorded
+	IsVisited(key string) (bool, error)
+	// Push adds a page to the pending pages
+	Push(n *node.WebNode) error
+	// Pop removes the oldest pending page, or the newest one if newest is set; it returns nil when none is pending
+	Pop(newest bool) (*node.WebNode, error)
+	// Len returns the number of pending pages
+	Len() int
+	// Close releases the resources of the store
+	Close() error
+}
+
+// MemoryFrontier is a FrontierStore holding the visited set and the pending pages in memory
+type MemoryFrontier struct {
+	visited map[string]bool
+	pending []*node.WebNode
+}
+
+// NewMemoryFrontier creates an empty in-memory frontier
+func NewMemoryFrontier() *MemoryFrontier {
+	return &MemoryFrontier{visited: make(map[string]bool)}
+}
+
+// MarkVisited records a normalized URL, reporting whether it was recorded before
+func (f *MemoryFrontier) MarkVisited(key string) (bool, error) {
+	visited := f.visited[key]
+	f.visited[key] = true
+	return visited, nil
+}
+
+// IsVisited reports whether a normalized URL was recorded
+func (f *MemoryFrontier) IsVisited(key string) (bool, error) {
+	return f.visited[key], nil
+}
+
+// Push adds a page to the pending pages
+func (f *MemoryFrontier) Push(n *node.WebNode) error {
+	f.pending = append(f.pending, n)
+	return nil
+}
+
+// Pop removes the oldest pending page, or the newest one if newest is set
+func (f *MemoryFrontier) Pop(newest bool) (*node.WebNode, error) {
+	if len(f.pending) == 0 {
+		return nil, nil
+	}
+
+	var n *node.WebNode
+	if newest {
+		n, f.pending = f.pending[len(f.pending)-1], f.pending[:len(f.pending)-1]
+	} else {
+		n, f.pending = f.pending[0], f.pending[1:]
+	}
+	return n, nil
+}
+
+// Len returns the number of pending pages
+func (f *MemoryFrontier) Len() int {
+	return len(f.pending)
+}
+
+// Close implements an empty method, as there is nothing to release
+func (f *MemoryFrontier) Close() error {
+	return nil
+}
+
+// rekey replaces every visited key with its value under normalize, for a change of URL normalization
+func (f *MemoryFrontier) rekey(normalize func(key string) string) {
+	visited := make(map[string]bool, len(f.visited))
+	for key := range f.visited {
+		visited[normalize(key)] = true
+	}
+	f.visited = visited
+}
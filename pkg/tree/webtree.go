@@ -10,11 +10,33 @@ import (
 
 // WebTree manages the entire website structure
 type WebTree struct {
-	RootNode    *node.WebNode   // Root node
-	MaxDepth    int             // Maximum exploration depth
-	VisitedURLs map[string]bool // Set of visited URLs
+	RootNode *node.WebNode   // Root node (the first seed)
+	Roots    []*node.WebNode // All root nodes, one per seed URL
+	MaxDepth int             // Maximum exploration depth
+	Frontier FrontierStore   // Visited URLs and pages waiting to be crawled; change with SetFrontier
+
+	// Whether added nodes are left out of their parent's Children, so crawled pages don't stay in memory.
+	// Set by SetFrontier for stores other than MemoryFrontier.
+	DetachNodes bool
+
+	normalization URLNormalization // URL equivalences applied when deduplicating; change with SetURLNormalization
+	queryPaths    map[string]bool  // Host and path of the URLs added to the tree, recorded when normalization.IgnoreQuery is set
+}
+
+// URLNormalization selects the equivalences under which two URLs are the same page.
+// Fragments and trailing slashes are always ignored.
+type URLNormalization struct {
+	CollapseIndexFiles bool // "/docs/index.html" is the same page as "/docs/"
+	LowercaseHost      bool // "Docs.Example.org" is the same host as "docs.example.org"
+
+	// Links to "/list?sort=date" are duplicates of "/list" once either is in the tree; see IsQueryVariant.
+	// URLs themselves keep their query, so seeds and next pages such as "/list?page=2" are distinct pages.
+	IgnoreQuery bool
 }
 
+// IndexFileNames are the directory index file names collapsed into the directory by URLNormalization.CollapseIndexFiles
+var IndexFileNames = []string{"index.html", "index.htm", "default.html", "default.htm"}
+
 // NewWebTree creates a new WebTree instance
 func NewWebTree(rootURL string, maxDepth int) (*WebTree, error) {
 	rootNode, err := node.NewWebNode(rootURL, nil)
@@ -22,26 +44,66 @@ func NewWebTree(rootURL string, maxDepth int) (*WebTree, error) {
 		return nil, fmt.Errorf("failed to create root node: %v", err)
 	}
 
-	return &WebTree{
-		RootNode:    rootNode,
-		MaxDepth:    maxDepth,
-		VisitedURLs: make(map[string]bool),
-	}, nil
+	t := &WebTree{
+		RootNode: rootNode,
+		Roots:    []*node.WebNode{rootNode},
+		MaxDepth: maxDepth,
+		Frontier: NewMemoryFrontier(),
+	}
+
+	// Roots are visited by definition
+	if _, err := t.Frontier.MarkVisited(t.normalizeURL(rootNode.URL)); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// SetFrontier replaces the store of visited URLs and pending pages, e.g. with a DiskFrontier for very large crawls.
+// It must be called before crawling: the roots are recorded as visited in the new store, other URLs are not carried over.
+// Nodes are detached from the tree unless the store is a MemoryFrontier.
+func (t *WebTree) SetFrontier(store FrontierStore) error {
+	for _, root := range t.Roots {
+		if _, err := store.MarkVisited(t.normalizeURL(root.URL)); err != nil {
+			return err
+		}
+	}
+
+	_, inMemory := store.(*MemoryFrontier)
+	t.Frontier = store
+	t.DetachNodes = !inMemory
+	return nil
+}
+
+// AddRoot adds another root node, used when crawling from multiple seed URLs.
+// It returns nil if the URL is already part of the tree.
+func (t *WebTree) AddRoot(urlStr string) (*node.WebNode, error) {
+	rootNode, err := t.AddURL(urlStr, nil)
+	if err != nil || rootNode == nil {
+		return nil, err
+	}
+
+	t.Roots = append(t.Roots, rootNode)
+	return rootNode, nil
 }
 
 // AddURL adds a URL to the appropriate position in the tree
 func (t *WebTree) AddURL(urlStr string, parentNode *node.WebNode) (*node.WebNode, error) {
-	// Parse URL
-	parsedURL, err := url.Parse(urlStr)
+	// Parse and validate URL
+	parsedURL, err := node.ParseURL(urlStr)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if URL has been visited
-	urlKey := t.normalizeURL(parsedURL)
-	if t.VisitedURLs[urlKey] {
+	// Check if URL has been visited, marking it visited otherwise
+	visited, err := t.Frontier.MarkVisited(t.normalizeURL(parsedURL))
+	if err != nil {
+		return nil, err
+	}
+	if visited {
 		return nil, nil // URL already exists in the tree
 	}
+	t.recordPath(parsedURL)
 
 	// Create new node
 	newNode, err := node.NewWebNode(urlStr, parentNode)
@@ -49,14 +111,13 @@ func (t *WebTree) AddURL(urlStr string, parentNode *node.WebNode) (*node.WebNode
 		return nil, err
 	}
 
-	// Add to parent node
-	if parentNode != nil {
+	// Add to parent node; detached nodes only keep their depth
+	if t.DetachNodes {
+		newNode.Parent = nil
+	} else if parentNode != nil {
 		parentNode.AddChild(newNode)
 	}
 
-	// Mark as visited
-	t.VisitedURLs[urlKey] = true
-
 	return newNode, nil
 }
 
@@ -67,8 +128,61 @@ func (t *WebTree) IsVisited(urlStr string) bool {
 		return false
 	}
 
-	urlKey := t.normalizeURL(parsedURL)
-	return t.VisitedURLs[urlKey]
+	visited, _ := t.Frontier.IsVisited(t.normalizeURL(parsedURL))
+	return visited
+}
+
+// IsQueryVariant reports whether a link differs only by its query string from a URL already in the tree,
+// when URLNormalization.IgnoreQuery is set. It is checked when accepting links, so that only the first
+// of "/list?sort=date" and "/list?sort=name" is crawled.
+func (t *WebTree) IsQueryVariant(urlStr string) bool {
+	if !t.normalization.IgnoreQuery {
+		return false
+	}
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil || t.IsVisited(urlStr) {
+		return false
+	}
+
+	return t.queryPaths[t.pathKey(parsedURL)]
+}
+
+// MarkVisited marks a URL as visited without adding a node, reporting whether it was already visited.
+// It is used for alias URLs such as a page's canonical URL.
+func (t *WebTree) MarkVisited(urlStr string) (bool, error) {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return false, err
+	}
+
+	return t.Frontier.MarkVisited(t.normalizeURL(parsedURL))
+}
+
+// SetURLNormalization chooses which URL equivalences are applied when deduplicating.
+// URLs visited so far are deduplicated again under the new rules; with a store other than MemoryFrontier,
+// only the roots are, so the normalization should be set before crawling.
+func (t *WebTree) SetURLNormalization(normalization URLNormalization) {
+	t.normalization = normalization
+	t.queryPaths = nil
+	if normalization.IgnoreQuery {
+		t.queryPaths = make(map[string]bool)
+		for _, root := range t.Roots {
+			t.recordPath(root.URL)
+		}
+	}
+
+	if memory, ok := t.Frontier.(*MemoryFrontier); ok {
+		memory.rekey(func(urlKey string) string {
+			if parsedURL, err := url.Parse(urlKey); err == nil {
+				return t.normalizeURL(parsedURL)
+			}
+			return urlKey
+		})
+		return
+	}
+	for _, root := range t.Roots {
+		t.Frontier.MarkVisited(t.normalizeURL(root.URL))
+	}
 }
 
 // IsAllowedDepth checks if exploration is allowed at the given depth
@@ -83,12 +197,20 @@ func (t *WebTree) FindNode(urlStr string) *node.WebNode {
 		return nil
 	}
 
-	return t.findNodeRecursive(t.RootNode, targetURL)
+	for _, root := range t.Roots {
+		if found := t.findNodeRecursive(root, targetURL); found != nil {
+			return found
+		}
+	}
+
+	return nil
 }
 
 // Print prints the entire tree structure
 func (t *WebTree) Print() {
-	t.printNode(t.RootNode, 0)
+	for _, root := range t.Roots {
+		t.printNode(root, 0)
+	}
 }
 
 // Helper methods
@@ -102,13 +224,42 @@ func (t *WebTree) normalizeURL(u *url.URL) string {
 	result := *u
 	result.Fragment = "" // Ignore fragment
 
+	if t.normalization.LowercaseHost {
+		result.Host = strings.ToLower(result.Host)
+	}
+	path := result.Path
+	if t.normalization.CollapseIndexFiles {
+		slash := strings.LastIndex(path, "/")
+		dir, file := path[:slash+1], path[slash+1:]
+		for _, indexFile := range IndexFileNames {
+			if strings.EqualFold(file, indexFile) {
+				path = dir
+				break
+			}
+		}
+	}
+
 	// Handle consistency of trailing slashes
-	path := strings.TrimRight(result.Path, "/")
-	result.Path = path
+	result.Path = strings.TrimRight(path, "/")
 
 	return result.String()
 }
 
+// pathKey returns the normalized URL without its query string, under which query variants are recorded
+func (t *WebTree) pathKey(u *url.URL) string {
+	result := *u
+	result.RawQuery = ""
+	result.ForceQuery = false
+	return t.normalizeURL(&result)
+}
+
+// recordPath records the host and path of a URL added to the tree, for IsQueryVariant
+func (t *WebTree) recordPath(u *url.URL) {
+	if t.queryPaths != nil {
+		t.queryPaths[t.pathKey(u)] = true
+	}
+}
+
 // findNodeRecursive recursively searches for a node
 func (t *WebTree) findNodeRecursive(current *node.WebNode, target *url.URL) *node.WebNode {
 	if current == nil {
@@ -0,0 +1,95 @@
+package tree
+
+import (
+	"testing"
+)
+
+func TestIgnoreQueryKeepsDistinctURLs(t *testing.T) {
+	webTree, err := NewWebTree("https://example.org/list", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webTree.SetURLNormalization(URLNormalization{IgnoreQuery: true})
+
+	// Seeds and next pages keep their query: "/list?page=2" is not "/list"
+	next, err := webTree.AddURL("https://example.org/list?page=2", webTree.RootNode)
+	if err != nil || next == nil {
+		t.Fatalf("AddURL(?page=2) = %v, %v; want a new node", next, err)
+	}
+	if webTree.IsVisited("https://example.org/list?page=3") {
+		t.Error("?page=3 is visited before being added")
+	}
+
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://example.org/list?sort=date", true},
+		{"https://example.org/list/?sort=name", true},
+		{"https://example.org/list", false},        // visited, not a variant
+		{"https://example.org/list?page=2", false}, // visited, not a variant
+		{"https://example.org/other?sort=date", false},
+		{"https://other.example.org/list?sort=date", false},
+	}
+	for _, tt := range tests {
+		if got := webTree.IsQueryVariant(tt.link); got != tt.want {
+			t.Errorf("IsQueryVariant(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestIncludeQueryPages(t *testing.T) {
+	webTree, err := NewWebTree("https://example.org/list", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webTree.SetURLNormalization(URLNormalization{})
+
+	if webTree.IsQueryVariant("https://example.org/list?sort=date") {
+		t.Error("query variants are filtered without IgnoreQuery")
+	}
+	if n, err := webTree.AddURL("https://example.org/list?sort=date", webTree.RootNode); err != nil || n == nil {
+		t.Errorf("AddURL(?sort=date) = %v, %v; want a new node", n, err)
+	}
+}
+
+func TestURLNormalizationRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalization URLNormalization
+		a, b          string
+		same          bool
+	}{
+		{"fragment", URLNormalization{}, "https://example.org/docs#intro", "https://example.org/docs", true},
+		{"trailing slash", URLNormalization{}, "https://example.org/docs/", "https://example.org/docs", true},
+
+		{"index.html collapsed", URLNormalization{CollapseIndexFiles: true}, "https://example.org/docs/index.html", "https://example.org/docs/", true},
+		{"index.htm collapsed", URLNormalization{CollapseIndexFiles: true}, "https://example.org/docs/INDEX.HTM", "https://example.org/docs", true},
+		{"default.html collapsed", URLNormalization{CollapseIndexFiles: true}, "https://example.org/docs/default.html", "https://example.org/docs/", true},
+		{"other file kept", URLNormalization{CollapseIndexFiles: true}, "https://example.org/docs/intro.html", "https://example.org/docs/", false},
+		{"index.html kept", URLNormalization{}, "https://example.org/docs/index.html", "https://example.org/docs/", false},
+
+		{"host lowercased", URLNormalization{LowercaseHost: true}, "https://Docs.Example.org/guide", "https://docs.example.org/guide", true},
+		{"path case kept", URLNormalization{LowercaseHost: true}, "https://example.org/Guide", "https://example.org/guide", false},
+		{"host case kept", URLNormalization{}, "https://Docs.Example.org/guide", "https://docs.example.org/guide", false},
+
+		{"query kept in keys", URLNormalization{IgnoreQuery: true}, "https://example.org/list?page=2", "https://example.org/list", false},
+		{"query kept", URLNormalization{}, "https://example.org/list?sort=date", "https://example.org/list", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webTree, err := NewWebTree("https://example.org/", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			webTree.SetURLNormalization(tt.normalization)
+
+			if _, err := webTree.AddURL(tt.a, webTree.RootNode); err != nil {
+				t.Fatal(err)
+			}
+			if got := webTree.IsVisited(tt.b); got != tt.same {
+				t.Errorf("%s visited after adding %s = %v, want %v", tt.b, tt.a, got, tt.same)
+			}
+		})
+	}
+}